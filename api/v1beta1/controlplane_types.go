@@ -17,31 +17,266 @@ limitations under the License.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// ServiceConfigSpec defines additional configuration of an OpenStack service
+type ServiceConfigSpec struct {
+	// oslo.config snippet in INI format added to the service config, e.g.
+	// "[DEFAULT]\ndebug = true"
+	CustomServiceConfig string `json:"customServiceConfig,omitempty"`
+	// files replacing the default config files of the service, keyed by file
+	// name, e.g. policy.yaml. Files ending in .conf or .ini have to be valid INI
+	DefaultConfigOverwrite map[string]string `json:"defaultConfigOverwrite,omitempty"`
+}
+
+// EndpointSpec defines the public endpoint of a service
+type EndpointSpec struct {
+	// public hostname of the service, defaults to the exposure hostname template
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// PodPlacementSpec defines the nodes the pods of a service get scheduled on.
+// Each field set on a service replaces the one of the default placement.
+type PodPlacementSpec struct {
+	// node labels the pods require, e.g. node-role.kubernetes.io/worker: ""
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// taints the pods tolerate
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// node and pod affinity rules of the pods
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+	// how the pods get spread across zones or nodes
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+}
+
+// PodDisruptionBudgetSpec defines the PodDisruptionBudgets of the API
+// services running more than one replica
+type PodDisruptionBudgetSpec struct {
+	// number or percentage of the API pods of a service which may be
+	// evicted at once, defaults to 1
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+const (
+	// ExposureRoute - expose the service APIs through OpenShift Routes
+	ExposureRoute = "route"
+	// ExposureIngress - expose the service APIs through Kubernetes Ingresses
+	ExposureIngress = "ingress"
+	// ExposureLoadBalancer - expose the service APIs through LoadBalancer Services,
+	// created with the pod selector of the service of the child operator once it exists
+	ExposureLoadBalancer = "loadbalancer"
+	// ExposureNone - do not expose the service APIs outside of the cluster
+	ExposureNone = "none"
+
+	// TLSTerminationNone - plain http
+	TLSTerminationNone = "none"
+	// TLSTerminationEdge - TLS gets terminated by the router or ingress controller
+	TLSTerminationEdge = "edge"
+	// TLSTerminationPassthrough - TLS gets terminated by the service
+	TLSTerminationPassthrough = "passthrough"
+	// TLSTerminationReencrypt - TLS gets terminated by the router and re-encrypted to the service
+	TLSTerminationReencrypt = "reencrypt"
+
+	// OverrideStrategic - strategic merge patch, custom resources get a merge patch
+	OverrideStrategic = "strategic"
+	// OverrideMerge - JSON merge patch
+	OverrideMerge = "merge"
+	// OverrideJSON - JSON patch
+	OverrideJSON = "json"
+
+	// PasswordRotationRotating - the passwords of the services get rotated
+	PasswordRotationRotating = "Rotating"
+	// PasswordRotationCompleted - the passwords of all services were rotated
+	PasswordRotationCompleted = "Completed"
+	// PasswordRotationFailed - a job changing the passwords failed
+	PasswordRotationFailed = "Failed"
+	// PasswordRotationUpdatingCredentials - the job changes the passwords of the database and Keystone users
+	PasswordRotationUpdatingCredentials = "UpdatingCredentials"
+	// PasswordRotationRollingOut - the service picks up the new secret
+	PasswordRotationRollingOut = "RollingOut"
+
+	// DeletionPolicyDelete - the data of the services gets deleted with the ControlPlane
+	DeletionPolicyDelete = "Delete"
+	// DeletionPolicyRetain - the services holding data and their volume claims are
+	// kept, as well as the secrets with the service passwords
+	DeletionPolicyRetain = "Retain"
+	// DeletionPolicySnapshot - the volume claims of the services holding data get
+	// snapshotted before they are deleted, the secrets with the service
+	// passwords are kept
+	DeletionPolicySnapshot = "Snapshot"
+)
+
+// OverrideSpec defines a patch applied to a rendered object before it gets
+// applied to the cluster
+type OverrideSpec struct {
+	// kind of the rendered object, e.g. GlanceAPI
+	Kind string `json:"kind"`
+	// name of the rendered object
+	Name string `json:"name"`
+	// strategic, merge or json, defaults to strategic
+	// +kubebuilder:validation:Enum=strategic;merge;json
+	Type string `json:"type,omitempty"`
+	// patch in YAML or JSON
+	Patch string `json:"patch"`
+}
+
+// BackupScheduleSpec defines the periodic backups of the MariaDB databases
+type BackupScheduleSpec struct {
+	// cron schedule of the backups, e.g. "0 2 * * *"
+	Schedule string `json:"schedule"`
+	// where the dumps get written to
+	Target BackupTarget `json:"target"`
+	// which of the dumps are kept
+	Retention BackupRetention `json:"retention,omitempty"`
+}
+
+// BackupRetention defines which scheduled dumps are kept, the latest dump is
+// never deleted
+type BackupRetention struct {
+	// number of dumps kept, 0 keeps all
+	Count int `json:"count,omitempty"`
+	// age after which dumps get deleted, e.g. 168h
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// ExposureSpec defines how the service APIs are made reachable from outside the cluster
+type ExposureSpec struct {
+	// route, ingress, loadbalancer or none, defaults to none
+	// +kubebuilder:validation:Enum=route;ingress;loadbalancer;none
+	Type string `json:"type,omitempty"`
+	// go template for the public hostnames, .Service, .Namespace and .PublicDomain
+	// can be used. Defaults to {{ .Service }}-{{ .Namespace }}.{{ .PublicDomain }}
+	HostnameTemplate string `json:"hostnameTemplate,omitempty"`
+	// none, edge, passthrough or reencrypt, defaults to none
+	// +kubebuilder:validation:Enum=none;edge;passthrough;reencrypt
+	TLSTermination string `json:"tlsTermination,omitempty"`
+	// secret with the TLS certificate used by ingresses for edge termination
+	TLSSecret string `json:"tlsSecret,omitempty"`
+}
+
+// KeystoneDomainSpec defines a Keystone domain with an LDAP identity backend
+type KeystoneDomainSpec struct {
+	// name of the Keystone domain
+	Name string `json:"name"`
+	// LDAP server URL, e.g. ldaps://ldap.example.com
+	URL string `json:"url"`
+	// secret with the BindDN and BindPassword keys used to query the LDAP server
+	BindSecret string `json:"bindSecret"`
+	// search base for users
+	UserTreeDN string `json:"userTreeDN"`
+	// LDAP object class for users, defaults to inetOrgPerson
+	UserObjectClass string `json:"userObjectClass,omitempty"`
+	// LDAP attribute mapped to the user name, defaults to uid
+	UserNameAttribute string `json:"userNameAttribute,omitempty"`
+	// search base for groups
+	GroupTreeDN string `json:"groupTreeDN,omitempty"`
+	// LDAP object class for groups, defaults to groupOfNames
+	GroupObjectClass string `json:"groupObjectClass,omitempty"`
+	// secret with the CA certificate (ca.crt) to verify the LDAP server
+	TLSCASecret string `json:"tlsCASecret,omitempty"`
+	// use StartTLS on a ldap:// URL
+	UseStartTLS bool `json:"useStartTLS,omitempty"`
+}
+
+// KeystoneIdentityProviderSpec defines a federated identity provider
+type KeystoneIdentityProviderSpec struct {
+	// name of the identity provider in Keystone
+	Name string `json:"name"`
+	// saml2 or openid
+	// +kubebuilder:validation:Enum=saml2;openid
+	Protocol string `json:"protocol"`
+	// remote IDs of the identity provider, e.g. the SAML entity ID
+	RemoteIDs []string `json:"remoteIDs"`
+	// URL of the SAML metadata or OpenID discovery document
+	MetadataURL string `json:"metadataURL,omitempty"`
+	// Keystone domain the federated users are created in
+	Domain string `json:"domain,omitempty"`
+	// Keystone mapping rules as JSON
+	Mapping string `json:"mapping"`
+}
+
+// KeystoneIdentitySpec defines the identity backends of Keystone
+type KeystoneIdentitySpec struct {
+	// domain specific LDAP backends
+	Domains []KeystoneDomainSpec `json:"domains,omitempty"`
+	// federated identity providers
+	IdentityProviders []KeystoneIdentityProviderSpec `json:"identityProviders,omitempty"`
+}
+
 // KeystoneSpec defines the desired state of KeystoneAPI
 type KeystoneSpec struct {
 	// number of Keystone API replicas
 	Replicas int `json:"replicas,omitempty"`
+	// public endpoint settings
+	Endpoint EndpointSpec `json:"endpoint,omitempty"`
+	// domain specific identity backends and federation
+	Identity KeystoneIdentitySpec `json:"identity,omitempty"`
+	// compute resources of the Keystone API pods
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// placement of the pods, overrides the default placement
+	PodPlacement PodPlacementSpec `json:"placement,omitempty"`
+	// existing secret with the Keystone credentials, e.g. synced from Vault by
+	// external-secrets. The keystone-secret doesn't get created then and an
+	// existing one gets deleted. Needs the AdminPassword and DatabasePassword
+	// keys.
+	SecretRef string `json:"secretRef,omitempty"`
+	// additional service config
+	ServiceConfigSpec `json:",inline"`
 }
 
 // GlanceSpec defines the desired state of GlanceAPI
 type GlanceSpec struct {
 	// number of Glance API replicas
 	Replicas int `json:"replicas,omitempty"`
+	// public endpoint settings
+	Endpoint EndpointSpec `json:"endpoint,omitempty"`
+	// compute resources of the Glance API pods
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// placement of the pods, overrides the default placement
+	PodPlacement PodPlacementSpec `json:"placement,omitempty"`
+	// existing secret replacing glance-secret, with the TransportUrl,
+	// DatabasePassword and GlanceKeystoneAuthPassword keys
+	SecretRef string `json:"secretRef,omitempty"`
+	// additional service config
+	ServiceConfigSpec `json:",inline"`
 }
 
 // PlacementSpec defines the desired state of PlacementAPI
 type PlacementSpec struct {
 	// number of Placement API replicas
 	Replicas int `json:"replicas,omitempty"`
+	// public endpoint settings
+	Endpoint EndpointSpec `json:"endpoint,omitempty"`
+	// compute resources of the Placement API pods
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// placement of the pods, overrides the default placement
+	PodPlacement PodPlacementSpec `json:"placement,omitempty"`
+	// existing secret replacing placement-secret, with the DatabasePassword
+	// and PlacementKeystoneAuthPassword keys
+	SecretRef string `json:"secretRef,omitempty"`
+	// additional service config
+	ServiceConfigSpec `json:",inline"`
+}
+
+// MariaDBSpec defines the desired state of MariaDB
+type MariaDBSpec struct {
+	// compute resources of the MariaDB pods
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// placement of the pods, overrides the default placement
+	PodPlacement PodPlacementSpec `json:"placement,omitempty"`
+	// existing secret with the DbRootPassword key replacing mariadb-secret,
+	// also used by the backup and restore jobs
+	SecretRef string `json:"secretRef,omitempty"`
 }
 
 // InterconnectSpec defines the desired state of Interconnect
 type InterconnectSpec struct {
 	// number of Interconnect
 	Replicas int `json:"replicas,omitempty"`
+	// compute resources of the Interconnect pods
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 // NovaSpec defines the desired state of Nova Control Plane
@@ -52,10 +287,34 @@ type NovaSpec struct {
 	NovaSchedulerReplicas int `json:"novaSchedulerReplicas,omitempty"`
 	// number of Nova Conductor replicas
 	NovaConductorReplicas int `json:"novaConductorReplicas,omitempty"`
+	// number of Nova Conductor replicas of cell1, defaults to novaConductorReplicas
+	NovaCell1ConductorReplicas *int `json:"novaCell1ConductorReplicas,omitempty"`
 	// number of Nova Metadata replicas
 	NovaMetadataReplicas int `json:"novaMetadataReplicas,omitempty"`
 	// number of Nova NoVNCProxy replicas
 	NovaNoVNCProxyReplicas int `json:"novaNoVNCProxyReplicas,omitempty"`
+	// public endpoint settings of the Nova API
+	Endpoint EndpointSpec `json:"endpoint,omitempty"`
+	// compute resources of all Nova pods, replaced by the ones of a component
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// compute resources of the Nova API pods
+	NovaAPIResources *corev1.ResourceRequirements `json:"novaAPIResources,omitempty"`
+	// compute resources of the Nova Scheduler pods
+	NovaSchedulerResources *corev1.ResourceRequirements `json:"novaSchedulerResources,omitempty"`
+	// compute resources of the Nova Conductor pods, also of cell1
+	NovaConductorResources *corev1.ResourceRequirements `json:"novaConductorResources,omitempty"`
+	// compute resources of the Nova Metadata pods
+	NovaMetadataResources *corev1.ResourceRequirements `json:"novaMetadataResources,omitempty"`
+	// compute resources of the Nova NoVNCProxy pods
+	NovaNoVNCProxyResources *corev1.ResourceRequirements `json:"novaNoVNCProxyResources,omitempty"`
+	// placement of the pods, overrides the default placement
+	PodPlacement PodPlacementSpec `json:"placement,omitempty"`
+	// existing secret replacing nova-secret, with the DatabasePassword and
+	// NovaKeystoneAuthPassword keys. The transport URL secrets of Nova are
+	// still created by the operator.
+	SecretRef string `json:"secretRef,omitempty"`
+	// additional service config
+	ServiceConfigSpec `json:",inline"`
 }
 
 // CinderSpec defines the desired state of Cinder Control Plane
@@ -69,18 +328,61 @@ type CinderSpec struct {
 	// number of Cinder Volume replicas
 	// Todo: how to handle different cinder volume services
 	CinderVolumeReplicas int `json:"cinderVolumeReplicas,omitempty"`
+	// public endpoint settings of the Cinder API
+	Endpoint EndpointSpec `json:"endpoint,omitempty"`
+	// compute resources of all Cinder pods, replaced by the ones of a component
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// compute resources of the Cinder API pods
+	CinderAPIResources *corev1.ResourceRequirements `json:"cinderAPIResources,omitempty"`
+	// compute resources of the Cinder Scheduler pods
+	CinderSchedulerResources *corev1.ResourceRequirements `json:"cinderSchedulerResources,omitempty"`
+	// compute resources of the Cinder Backup pods
+	CinderBackupResources *corev1.ResourceRequirements `json:"cinderBackupResources,omitempty"`
+	// compute resources of the Cinder Volume pods
+	CinderVolumeResources *corev1.ResourceRequirements `json:"cinderVolumeResources,omitempty"`
+	// placement of the pods, overrides the default placement
+	PodPlacement PodPlacementSpec `json:"placement,omitempty"`
+	// existing secret replacing cinder-secret, with the TransportUrl,
+	// DatabasePassword and CinderKeystoneAuthPassword keys
+	SecretRef string `json:"secretRef,omitempty"`
+	// additional service config
+	ServiceConfigSpec `json:",inline"`
 }
 
 // NeutronSpec defines the desired state of NeutronAPI
 type NeutronSpec struct {
 	// number of Neutron API replicas
 	Replicas int `json:"replicas,omitempty"`
+	// public endpoint settings
+	Endpoint EndpointSpec `json:"endpoint,omitempty"`
+	// compute resources of the Neutron API pods
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// placement of the pods, overrides the default placement
+	PodPlacement PodPlacementSpec `json:"placement,omitempty"`
+	// existing secret replacing neutron-secret, with the TransportUrl,
+	// DatabasePassword and NeutronKeystoneAuthPassword keys
+	SecretRef string `json:"secretRef,omitempty"`
+	// additional service config
+	ServiceConfigSpec `json:",inline"`
 }
 
 // ControlPlaneSpec defines the desired state of ControlPlane
 type ControlPlaneSpec struct {
 	// storage class to use for storage claims
 	StorageClass string `json:"storage_class,omitempty"`
+	// region the service endpoints get registered in, defaults to regionOne
+	Region string `json:"region,omitempty"`
+	// domain the public service hostnames are created in
+	PublicDomain string `json:"publicDomain,omitempty"`
+	// external exposure of the service APIs
+	Exposure ExposureSpec `json:"exposure,omitempty"`
+	// placement of the pods of all services, except the AMQ Interconnect
+	// which has no placement settings
+	DefaultPlacement PodPlacementSpec `json:"defaultPlacement,omitempty"`
+	// disruption budgets of the API services with more than one replica
+	PodDisruptionBudget PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
+	// MariaDB settings
+	MariaDB MariaDBSpec `json:"mariadb,omitempty"`
 	// Keystone API settings
 	Keystone KeystoneSpec `json:"keystone,omitempty"`
 	// Glance API settings
@@ -95,16 +397,88 @@ type ControlPlaneSpec struct {
 	Cinder CinderSpec `json:"cinder,omitempty"`
 	// Neutron settings
 	Neutron NeutronSpec `json:"neutron,omitempty"`
+	// patches applied to the rendered objects
+	Overrides []OverrideSpec `json:"overrides,omitempty"`
+	// what happens to the data of MariaDB and Glance when the ControlPlane
+	// gets deleted: Delete, Retain or Snapshot, defaults to Delete. Retain and
+	// Snapshot also keep the secrets with the database and Keystone
+	// passwords of the services, detached from the ControlPlane, as the data
+	// can't be used without them.
+	// +kubebuilder:validation:Enum=Delete;Retain;Snapshot
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+	// periodic backups of the MariaDB databases, suspended during restores.
+	// Removing the schedule deletes the CronJob, the dumps are kept.
+	BackupSchedule *BackupScheduleSpec `json:"backupSchedule,omitempty"`
+	// interval in which the database and Keystone service user passwords
+	// of the services get rotated, e.g. 720h. A rotation can also be
+	// triggered by setting the controlplane.openstack.org/rotate-passwords
+	// annotation to a new value. The services are rotated one at a time and
+	// each one has an outage: the users get the new passwords before the
+	// pods of the service are restarted with them, new connections of the
+	// running pods fail until the rollout finished.
+	RotationPeriod *metav1.Duration `json:"rotationPeriod,omitempty"`
 }
 
 // ControlPlaneStatus defines the observed state of ControlPlane
 type ControlPlaneStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// external URLs of the exposed service APIs, keyed by service
+	Endpoints map[string]string `json:"endpoints,omitempty"`
+	// rollout phase currently applied, starting at 1
+	RolloutPhase int `json:"rolloutPhase,omitempty"`
+	// services of the current rollout phase
+	RolloutServices []string `json:"rolloutServices,omitempty"`
+	// objects of the current rollout phase which are not ready yet, as Kind/name
+	WaitingFor []string `json:"waitingFor,omitempty"`
+	// custom resources whose status doesn't tell if they are ready, as
+	// Kind/name. A rollout phase waits for them up to five minutes, then they
+	// are assumed to be ready.
+	AssumedReady []string `json:"assumedReady,omitempty"`
+	// time the current rollout phase was entered
+	RolloutPhaseStartTime *metav1.Time `json:"rolloutPhaseStartTime,omitempty"`
+	// objects which failed to apply during the last reconcile, as Kind/name: error
+	ApplyErrors []string `json:"applyErrors,omitempty"`
+	// referenced service secrets which don't exist or miss required keys,
+	// nothing gets applied until they are fixed
+	SecretErrors []string `json:"secretErrors,omitempty"`
+	// true when all rollout phases are applied and ready
+	Ready bool `json:"ready,omitempty"`
+	// true when the API services are scaled down for a restore, all rollout
+	// phases are applied and ready and the pods of the API services are gone
+	Quiesced bool `json:"quiesced,omitempty"`
+	// completion time of the last successful scheduled backup
+	LastBackupTime *metav1.Time `json:"lastBackupTime,omitempty"`
+	// size of the dump of the last successful scheduled backup in bytes
+	LastBackupSize int64 `json:"lastBackupSize,omitempty"`
+	// file name or URL of the dump of the last successful scheduled backup
+	LastBackupLocation string `json:"lastBackupLocation,omitempty"`
+	// progress of the password rotation
+	PasswordRotation PasswordRotationStatus `json:"passwordRotation,omitempty"`
+}
+
+// PasswordRotationStatus defines the progress of the password rotation, the
+// services get rotated one after the other in rollout order
+type PasswordRotationStatus struct {
+	// Rotating, Completed or Failed
+	Phase string `json:"phase,omitempty"`
+	// service whose passwords get rotated
+	Service string `json:"service,omitempty"`
+	// UpdatingCredentials while the job changes the passwords of the database
+	// and Keystone users, RollingOut while the service picks up the new secret
+	Step string `json:"step,omitempty"`
+	// value of the rotate-passwords annotation the last rotation was started for
+	Trigger string `json:"trigger,omitempty"`
+	// time the current or last rotation was started
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// completion time of the last successful rotation
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+	// reason of a failed rotation
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=integer,JSONPath=`.status.rolloutPhase`
+// +kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
 
 // ControlPlane is the Schema for the controlplanes API
 type ControlPlane struct {
@@ -0,0 +1,88 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BackupTarget defines where a database dump gets written to, exactly one of
+// persistentVolumeClaim and s3 has to be set
+type BackupTarget struct {
+	// existing PersistentVolumeClaim in the namespace of the ControlPlane
+	PersistentVolumeClaim string `json:"persistentVolumeClaim,omitempty"`
+	// S3-compatible object storage
+	S3 *S3Target `json:"s3,omitempty"`
+}
+
+// S3Target defines a bucket of an S3-compatible object storage
+type S3Target struct {
+	// URL of the S3 API, e.g. https://s3.example.com
+	Endpoint string `json:"endpoint"`
+	// bucket the dumps are stored in
+	Bucket string `json:"bucket"`
+	// prefix of the object keys of the dumps
+	Prefix string `json:"prefix,omitempty"`
+	// secret with the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY of the bucket
+	CredentialsSecret string `json:"credentialsSecret"`
+}
+
+// ControlPlaneBackupSpec defines the desired state of ControlPlaneBackup
+type ControlPlaneBackupSpec struct {
+	// name of the ControlPlane whose MariaDB gets dumped, in the same namespace
+	ControlPlane string `json:"controlPlane"`
+	// where the dump gets written to
+	Target BackupTarget `json:"target"`
+}
+
+// ControlPlaneBackupStatus defines the observed state of ControlPlaneBackup
+type ControlPlaneBackupStatus struct {
+	// Pending, Running, Succeeded or Failed
+	Phase string `json:"phase,omitempty"`
+	// file name of the dump in the volume claim or object key in the bucket
+	Location string `json:"location,omitempty"`
+	// time the job completed or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="ControlPlane",type=string,JSONPath=`.spec.controlPlane`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Location",type=string,JSONPath=`.status.location`
+
+// ControlPlaneBackup is the Schema for the controlplanebackups API
+type ControlPlaneBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ControlPlaneBackupSpec   `json:"spec,omitempty"`
+	Status ControlPlaneBackupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ControlPlaneBackupList contains a list of ControlPlaneBackup
+type ControlPlaneBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ControlPlaneBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ControlPlaneBackup{}, &ControlPlaneBackupList{})
+}
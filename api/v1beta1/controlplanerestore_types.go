@@ -0,0 +1,74 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// RestorePhaseQuiescing - the API services get scaled down to 0 replicas
+	RestorePhaseQuiescing = "Quiescing"
+	// RestorePhaseRestoring - the job loads the dump into the MariaDB
+	RestorePhaseRestoring = "Restoring"
+	// RestorePhaseResuming - the API services get scaled up again
+	RestorePhaseResuming = "Resuming"
+)
+
+// ControlPlaneRestoreSpec defines the desired state of ControlPlaneRestore
+type ControlPlaneRestoreSpec struct {
+	// name of the succeeded ControlPlaneBackup to restore, in the same
+	// namespace. The dump gets loaded into the MariaDB of its ControlPlane.
+	Backup string `json:"backup"`
+}
+
+// ControlPlaneRestoreStatus defines the observed state of ControlPlaneRestore
+type ControlPlaneRestoreStatus struct {
+	// Pending, Quiescing, Restoring, Resuming, Succeeded or Failed
+	Phase string `json:"phase,omitempty"`
+	// Succeeded or Failed once the restore job finished
+	JobPhase string `json:"jobPhase,omitempty"`
+	// time the API services were resumed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Backup",type=string,JSONPath=`.spec.backup`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+
+// ControlPlaneRestore is the Schema for the controlplanerestores API
+type ControlPlaneRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ControlPlaneRestoreSpec   `json:"spec,omitempty"`
+	Status ControlPlaneRestoreStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ControlPlaneRestoreList contains a list of ControlPlaneRestore
+type ControlPlaneRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ControlPlaneRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ControlPlaneRestore{}, &ControlPlaneRestoreList{})
+}
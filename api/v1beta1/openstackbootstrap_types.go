@@ -0,0 +1,148 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BootstrapProject defines a Keystone project and its quotas
+type BootstrapProject struct {
+	Name string `json:"name"`
+	// Keystone domain of the project, defaults to "Default"
+	Domain      string `json:"domain,omitempty"`
+	Description string `json:"description,omitempty"`
+	// quotas keyed by the "openstack quota set" option, e.g. cores, instances, ram
+	Quotas map[string]int `json:"quotas,omitempty"`
+}
+
+// BootstrapFlavor defines a Nova flavor
+type BootstrapFlavor struct {
+	Name  string `json:"name"`
+	VCPUs int    `json:"vcpus"`
+	// memory in MB
+	RAM int `json:"ram"`
+	// root disk in GB
+	Disk int `json:"disk,omitempty"`
+	// private flavors are only visible to the admin project
+	Private bool `json:"private,omitempty"`
+	// extra specs of the flavor
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// BootstrapSubnet defines a Neutron subnet
+type BootstrapSubnet struct {
+	Name string `json:"name"`
+	// subnet range in CIDR notation
+	CIDR    string `json:"cidr"`
+	Gateway string `json:"gateway,omitempty"`
+	// first and last IP of the allocation pool
+	AllocationPoolStart string `json:"allocationPoolStart,omitempty"`
+	AllocationPoolEnd   string `json:"allocationPoolEnd,omitempty"`
+	DisableDHCP         bool   `json:"disableDHCP,omitempty"`
+}
+
+// BootstrapNetwork defines a Neutron network and its subnets
+type BootstrapNetwork struct {
+	Name string `json:"name"`
+	// project owning the network, defaults to the admin project
+	Project  string `json:"project,omitempty"`
+	External bool   `json:"external,omitempty"`
+	Shared   bool   `json:"shared,omitempty"`
+	// provider network type, e.g. flat or vlan
+	ProviderNetworkType     string            `json:"providerNetworkType,omitempty"`
+	ProviderPhysicalNetwork string            `json:"providerPhysicalNetwork,omitempty"`
+	ProviderSegment         *int              `json:"providerSegment,omitempty"`
+	Subnets                 []BootstrapSubnet `json:"subnets,omitempty"`
+}
+
+// BootstrapImage defines a Glance image
+type BootstrapImage struct {
+	Name string `json:"name"`
+	// URL the image gets downloaded from
+	URL string `json:"url"`
+	// disk format, defaults to qcow2
+	DiskFormat string `json:"diskFormat,omitempty"`
+	// container format, defaults to bare
+	ContainerFormat string `json:"containerFormat,omitempty"`
+	Public          bool   `json:"public,omitempty"`
+}
+
+// OpenStackBootstrapSpec defines the desired state of OpenStackBootstrap
+type OpenStackBootstrapSpec struct {
+	// name of the ControlPlane the resources get created in
+	ControlPlane string `json:"controlPlane"`
+	// name of the OpenStackClient the image and cloud config are taken from
+	OpenStackClient string `json:"openStackClient"`
+	// Keystone projects
+	Projects []BootstrapProject `json:"projects,omitempty"`
+	// Neutron networks
+	Networks []BootstrapNetwork `json:"networks,omitempty"`
+	// Nova flavors
+	Flavors []BootstrapFlavor `json:"flavors,omitempty"`
+	// Glance images
+	Images []BootstrapImage `json:"images,omitempty"`
+}
+
+// BootstrapItemStatus defines the observed state of a single bootstrap resource
+type BootstrapItemStatus struct {
+	// Project, Network, Flavor or Image
+	Kind string `json:"kind"`
+	// name of the resource, projects are prefixed with their domain
+	Name string `json:"name"`
+	// Pending, Running, Succeeded or Failed
+	Phase string `json:"phase"`
+	// name of the job converging the resource
+	Job string `json:"job,omitempty"`
+	// number of times the job got recreated after it failed
+	Retries int32 `json:"retries,omitempty"`
+	// time the job completed or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// OpenStackBootstrapStatus defines the observed state of OpenStackBootstrap
+type OpenStackBootstrapStatus struct {
+	// true when all resources converged
+	Ready bool                  `json:"ready"`
+	Items []BootstrapItemStatus `json:"items,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=boolean,JSONPath=`.status.ready`
+
+// OpenStackBootstrap is the Schema for the openstackbootstraps API
+type OpenStackBootstrap struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OpenStackBootstrapSpec   `json:"spec,omitempty"`
+	Status OpenStackBootstrapStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OpenStackBootstrapList contains a list of OpenStackBootstrap
+type OpenStackBootstrapList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OpenStackBootstrap `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OpenStackBootstrap{}, &OpenStackBootstrapList{})
+}
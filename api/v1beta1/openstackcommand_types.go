@@ -0,0 +1,94 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CommandPhasePending - the job was created but has not started yet
+	CommandPhasePending = "Pending"
+	// CommandPhaseRunning - the job is running the commands
+	CommandPhaseRunning = "Running"
+	// CommandPhaseSucceeded - all commands exited with 0
+	CommandPhaseSucceeded = "Succeeded"
+	// CommandPhaseFailed - a command failed and all retries of the job are
+	// used up, the job gets recreated after a backoff
+	CommandPhaseFailed = "Failed"
+)
+
+// OpenStackCommandSpec defines the desired state of OpenStackCommand
+type OpenStackCommandSpec struct {
+	// name of the OpenStackClient the image and cloud config are taken from
+	OpenStackClient string `json:"openStackClient"`
+	// openstack CLI invocations without the leading "openstack", run in order,
+	// e.g. "flavor create --ram 512 --disk 1 --vcpus 1 m1.tiny"
+	Commands []string `json:"commands"`
+	// number of retries before the job is marked as failed
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+	// number of times the job gets recreated after it failed, defaults to 3
+	// +kubebuilder:validation:Minimum=0
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+	// seconds after the job finished before it gets deleted
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+}
+
+// OpenStackCommandStatus defines the observed state of OpenStackCommand
+type OpenStackCommandStatus struct {
+	// hash of the spec the current job was created for
+	JobHash string `json:"jobHash,omitempty"`
+	// Pending, Running, Succeeded or Failed
+	Phase string `json:"phase,omitempty"`
+	// number of job attempts
+	Attempts int32 `json:"attempts,omitempty"`
+	// number of times the job got recreated after it failed
+	Retries int32 `json:"retries,omitempty"`
+	// exit code of the last attempt
+	ExitCode *int32 `json:"exitCode,omitempty"`
+	// truncated output of the last attempt
+	Output string `json:"output,omitempty"`
+	// time the job completed or failed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Exit Code",type=integer,JSONPath=`.status.exitCode`
+
+// OpenStackCommand is the Schema for the openstackcommands API
+type OpenStackCommand struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OpenStackCommandSpec   `json:"spec,omitempty"`
+	Status OpenStackCommandStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// OpenStackCommandList contains a list of OpenStackCommand
+type OpenStackCommandList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OpenStackCommand `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OpenStackCommand{}, &OpenStackCommandList{})
+}
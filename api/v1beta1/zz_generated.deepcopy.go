@@ -21,45 +21,822 @@ limitations under the License.
 package v1beta1
 
 import (
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupRetention) DeepCopyInto(out *BackupRetention) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupRetention.
+func (in *BackupRetention) DeepCopy() *BackupRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupScheduleSpec) DeepCopyInto(out *BackupScheduleSpec) {
+	*out = *in
+	in.Target.DeepCopyInto(&out.Target)
+	in.Retention.DeepCopyInto(&out.Retention)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupScheduleSpec.
+func (in *BackupScheduleSpec) DeepCopy() *BackupScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupTarget) DeepCopyInto(out *BackupTarget) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3Target)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupTarget.
+func (in *BackupTarget) DeepCopy() *BackupTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapFlavor) DeepCopyInto(out *BootstrapFlavor) {
+	*out = *in
+	if in.Properties != nil {
+		in, out := &in.Properties, &out.Properties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapFlavor.
+func (in *BootstrapFlavor) DeepCopy() *BootstrapFlavor {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapFlavor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapImage) DeepCopyInto(out *BootstrapImage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapImage.
+func (in *BootstrapImage) DeepCopy() *BootstrapImage {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapItemStatus) DeepCopyInto(out *BootstrapItemStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapItemStatus.
+func (in *BootstrapItemStatus) DeepCopy() *BootstrapItemStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapItemStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapNetwork) DeepCopyInto(out *BootstrapNetwork) {
+	*out = *in
+	if in.ProviderSegment != nil {
+		in, out := &in.ProviderSegment, &out.ProviderSegment
+		*out = new(int)
+		**out = **in
+	}
+	if in.Subnets != nil {
+		in, out := &in.Subnets, &out.Subnets
+		*out = make([]BootstrapSubnet, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapNetwork.
+func (in *BootstrapNetwork) DeepCopy() *BootstrapNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapProject) DeepCopyInto(out *BootstrapProject) {
+	*out = *in
+	if in.Quotas != nil {
+		in, out := &in.Quotas, &out.Quotas
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapProject.
+func (in *BootstrapProject) DeepCopy() *BootstrapProject {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapProject)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootstrapSubnet) DeepCopyInto(out *BootstrapSubnet) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootstrapSubnet.
+func (in *BootstrapSubnet) DeepCopy() *BootstrapSubnet {
+	if in == nil {
+		return nil
+	}
+	out := new(BootstrapSubnet)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CinderSpec) DeepCopyInto(out *CinderSpec) {
 	*out = *in
+	out.Endpoint = in.Endpoint
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.CinderAPIResources != nil {
+		in, out := &in.CinderAPIResources, &out.CinderAPIResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CinderSchedulerResources != nil {
+		in, out := &in.CinderSchedulerResources, &out.CinderSchedulerResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CinderBackupResources != nil {
+		in, out := &in.CinderBackupResources, &out.CinderBackupResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CinderVolumeResources != nil {
+		in, out := &in.CinderVolumeResources, &out.CinderVolumeResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	in.ServiceConfigSpec.DeepCopyInto(&out.ServiceConfigSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CinderSpec.
+func (in *CinderSpec) DeepCopy() *CinderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CinderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlane) DeepCopyInto(out *ControlPlane) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlane.
+func (in *ControlPlane) DeepCopy() *ControlPlane {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlane)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControlPlane) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneBackup) DeepCopyInto(out *ControlPlaneBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneBackup.
+func (in *ControlPlaneBackup) DeepCopy() *ControlPlaneBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControlPlaneBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneBackupList) DeepCopyInto(out *ControlPlaneBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ControlPlaneBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneBackupList.
+func (in *ControlPlaneBackupList) DeepCopy() *ControlPlaneBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControlPlaneBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneBackupSpec) DeepCopyInto(out *ControlPlaneBackupSpec) {
+	*out = *in
+	in.Target.DeepCopyInto(&out.Target)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneBackupSpec.
+func (in *ControlPlaneBackupSpec) DeepCopy() *ControlPlaneBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneBackupStatus) DeepCopyInto(out *ControlPlaneBackupStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneBackupStatus.
+func (in *ControlPlaneBackupStatus) DeepCopy() *ControlPlaneBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneList) DeepCopyInto(out *ControlPlaneList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ControlPlane, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneList.
+func (in *ControlPlaneList) DeepCopy() *ControlPlaneList {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControlPlaneList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneRestore) DeepCopyInto(out *ControlPlaneRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneRestore.
+func (in *ControlPlaneRestore) DeepCopy() *ControlPlaneRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControlPlaneRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneRestoreList) DeepCopyInto(out *ControlPlaneRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ControlPlaneRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneRestoreList.
+func (in *ControlPlaneRestoreList) DeepCopy() *ControlPlaneRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControlPlaneRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneRestoreSpec) DeepCopyInto(out *ControlPlaneRestoreSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneRestoreSpec.
+func (in *ControlPlaneRestoreSpec) DeepCopy() *ControlPlaneRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneRestoreStatus) DeepCopyInto(out *ControlPlaneRestoreStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneRestoreStatus.
+func (in *ControlPlaneRestoreStatus) DeepCopy() *ControlPlaneRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneSpec) DeepCopyInto(out *ControlPlaneSpec) {
+	*out = *in
+	out.Exposure = in.Exposure
+	in.DefaultPlacement.DeepCopyInto(&out.DefaultPlacement)
+	in.PodDisruptionBudget.DeepCopyInto(&out.PodDisruptionBudget)
+	in.MariaDB.DeepCopyInto(&out.MariaDB)
+	in.Keystone.DeepCopyInto(&out.Keystone)
+	in.Glance.DeepCopyInto(&out.Glance)
+	in.Placement.DeepCopyInto(&out.Placement)
+	in.Interconnect.DeepCopyInto(&out.Interconnect)
+	in.Nova.DeepCopyInto(&out.Nova)
+	in.Cinder.DeepCopyInto(&out.Cinder)
+	in.Neutron.DeepCopyInto(&out.Neutron)
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make([]OverrideSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.BackupSchedule != nil {
+		in, out := &in.BackupSchedule, &out.BackupSchedule
+		*out = new(BackupScheduleSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RotationPeriod != nil {
+		in, out := &in.RotationPeriod, &out.RotationPeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneSpec.
+func (in *ControlPlaneSpec) DeepCopy() *ControlPlaneSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControlPlaneStatus) DeepCopyInto(out *ControlPlaneStatus) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RolloutServices != nil {
+		in, out := &in.RolloutServices, &out.RolloutServices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.WaitingFor != nil {
+		in, out := &in.WaitingFor, &out.WaitingFor
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AssumedReady != nil {
+		in, out := &in.AssumedReady, &out.AssumedReady
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RolloutPhaseStartTime != nil {
+		in, out := &in.RolloutPhaseStartTime, &out.RolloutPhaseStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ApplyErrors != nil {
+		in, out := &in.ApplyErrors, &out.ApplyErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretErrors != nil {
+		in, out := &in.SecretErrors, &out.SecretErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastBackupTime != nil {
+		in, out := &in.LastBackupTime, &out.LastBackupTime
+		*out = (*in).DeepCopy()
+	}
+	in.PasswordRotation.DeepCopyInto(&out.PasswordRotation)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneStatus.
+func (in *ControlPlaneStatus) DeepCopy() *ControlPlaneStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ControlPlaneStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EndpointSpec) DeepCopyInto(out *EndpointSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointSpec.
+func (in *EndpointSpec) DeepCopy() *EndpointSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EndpointSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExposureSpec) DeepCopyInto(out *ExposureSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExposureSpec.
+func (in *ExposureSpec) DeepCopy() *ExposureSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExposureSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlanceSpec) DeepCopyInto(out *GlanceSpec) {
+	*out = *in
+	out.Endpoint = in.Endpoint
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	in.ServiceConfigSpec.DeepCopyInto(&out.ServiceConfigSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlanceSpec.
+func (in *GlanceSpec) DeepCopy() *GlanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GlanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InterconnectSpec) DeepCopyInto(out *InterconnectSpec) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InterconnectSpec.
+func (in *InterconnectSpec) DeepCopy() *InterconnectSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InterconnectSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeystoneDomainSpec) DeepCopyInto(out *KeystoneDomainSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoneDomainSpec.
+func (in *KeystoneDomainSpec) DeepCopy() *KeystoneDomainSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeystoneDomainSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeystoneIdentityProviderSpec) DeepCopyInto(out *KeystoneIdentityProviderSpec) {
+	*out = *in
+	if in.RemoteIDs != nil {
+		in, out := &in.RemoteIDs, &out.RemoteIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoneIdentityProviderSpec.
+func (in *KeystoneIdentityProviderSpec) DeepCopy() *KeystoneIdentityProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeystoneIdentityProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeystoneIdentitySpec) DeepCopyInto(out *KeystoneIdentitySpec) {
+	*out = *in
+	if in.Domains != nil {
+		in, out := &in.Domains, &out.Domains
+		*out = make([]KeystoneDomainSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.IdentityProviders != nil {
+		in, out := &in.IdentityProviders, &out.IdentityProviders
+		*out = make([]KeystoneIdentityProviderSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoneIdentitySpec.
+func (in *KeystoneIdentitySpec) DeepCopy() *KeystoneIdentitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeystoneIdentitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeystoneSpec) DeepCopyInto(out *KeystoneSpec) {
+	*out = *in
+	out.Endpoint = in.Endpoint
+	in.Identity.DeepCopyInto(&out.Identity)
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	in.ServiceConfigSpec.DeepCopyInto(&out.ServiceConfigSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoneSpec.
+func (in *KeystoneSpec) DeepCopy() *KeystoneSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeystoneSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MariaDBSpec) DeepCopyInto(out *MariaDBSpec) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MariaDBSpec.
+func (in *MariaDBSpec) DeepCopy() *MariaDBSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MariaDBSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NeutronSpec) DeepCopyInto(out *NeutronSpec) {
+	*out = *in
+	out.Endpoint = in.Endpoint
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	in.ServiceConfigSpec.DeepCopyInto(&out.ServiceConfigSpec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CinderSpec.
-func (in *CinderSpec) DeepCopy() *CinderSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeutronSpec.
+func (in *NeutronSpec) DeepCopy() *NeutronSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(CinderSpec)
+	out := new(NeutronSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ControlPlane) DeepCopyInto(out *ControlPlane) {
+func (in *NovaSpec) DeepCopyInto(out *NovaSpec) {
+	*out = *in
+	if in.NovaCell1ConductorReplicas != nil {
+		in, out := &in.NovaCell1ConductorReplicas, &out.NovaCell1ConductorReplicas
+		*out = new(int)
+		**out = **in
+	}
+	out.Endpoint = in.Endpoint
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.NovaAPIResources != nil {
+		in, out := &in.NovaAPIResources, &out.NovaAPIResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NovaSchedulerResources != nil {
+		in, out := &in.NovaSchedulerResources, &out.NovaSchedulerResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NovaConductorResources != nil {
+		in, out := &in.NovaConductorResources, &out.NovaConductorResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NovaMetadataResources != nil {
+		in, out := &in.NovaMetadataResources, &out.NovaMetadataResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NovaNoVNCProxyResources != nil {
+		in, out := &in.NovaNoVNCProxyResources, &out.NovaNoVNCProxyResources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	in.ServiceConfigSpec.DeepCopyInto(&out.ServiceConfigSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NovaSpec.
+func (in *NovaSpec) DeepCopy() *NovaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NovaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpenStackBootstrap) DeepCopyInto(out *OpenStackBootstrap) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlane.
-func (in *ControlPlane) DeepCopy() *ControlPlane {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackBootstrap.
+func (in *OpenStackBootstrap) DeepCopy() *OpenStackBootstrap {
 	if in == nil {
 		return nil
 	}
-	out := new(ControlPlane)
+	out := new(OpenStackBootstrap)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ControlPlane) DeepCopyObject() runtime.Object {
+func (in *OpenStackBootstrap) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -67,31 +844,31 @@ func (in *ControlPlane) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ControlPlaneList) DeepCopyInto(out *ControlPlaneList) {
+func (in *OpenStackBootstrapList) DeepCopyInto(out *OpenStackBootstrapList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ControlPlane, len(*in))
+		*out = make([]OpenStackBootstrap, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneList.
-func (in *ControlPlaneList) DeepCopy() *ControlPlaneList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackBootstrapList.
+func (in *OpenStackBootstrapList) DeepCopy() *OpenStackBootstrapList {
 	if in == nil {
 		return nil
 	}
-	out := new(ControlPlaneList)
+	out := new(OpenStackBootstrapList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ControlPlaneList) DeepCopyObject() runtime.Object {
+func (in *OpenStackBootstrapList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -99,138 +876,178 @@ func (in *ControlPlaneList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ControlPlaneSpec) DeepCopyInto(out *ControlPlaneSpec) {
+func (in *OpenStackBootstrapSpec) DeepCopyInto(out *OpenStackBootstrapSpec) {
 	*out = *in
-	out.Keystone = in.Keystone
-	out.Glance = in.Glance
-	out.Placement = in.Placement
-	out.Interconnect = in.Interconnect
-	out.Nova = in.Nova
-	out.Cinder = in.Cinder
-	out.Neutron = in.Neutron
+	if in.Projects != nil {
+		in, out := &in.Projects, &out.Projects
+		*out = make([]BootstrapProject, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Networks != nil {
+		in, out := &in.Networks, &out.Networks
+		*out = make([]BootstrapNetwork, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Flavors != nil {
+		in, out := &in.Flavors, &out.Flavors
+		*out = make([]BootstrapFlavor, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]BootstrapImage, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneSpec.
-func (in *ControlPlaneSpec) DeepCopy() *ControlPlaneSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackBootstrapSpec.
+func (in *OpenStackBootstrapSpec) DeepCopy() *OpenStackBootstrapSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ControlPlaneSpec)
+	out := new(OpenStackBootstrapSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ControlPlaneStatus) DeepCopyInto(out *ControlPlaneStatus) {
+func (in *OpenStackBootstrapStatus) DeepCopyInto(out *OpenStackBootstrapStatus) {
 	*out = *in
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BootstrapItemStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControlPlaneStatus.
-func (in *ControlPlaneStatus) DeepCopy() *ControlPlaneStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackBootstrapStatus.
+func (in *OpenStackBootstrapStatus) DeepCopy() *OpenStackBootstrapStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ControlPlaneStatus)
+	out := new(OpenStackBootstrapStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GlanceSpec) DeepCopyInto(out *GlanceSpec) {
+func (in *OpenStackClient) DeepCopyInto(out *OpenStackClient) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status = in.Status
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlanceSpec.
-func (in *GlanceSpec) DeepCopy() *GlanceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackClient.
+func (in *OpenStackClient) DeepCopy() *OpenStackClient {
 	if in == nil {
 		return nil
 	}
-	out := new(GlanceSpec)
+	out := new(OpenStackClient)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *InterconnectSpec) DeepCopyInto(out *InterconnectSpec) {
-	*out = *in
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InterconnectSpec.
-func (in *InterconnectSpec) DeepCopy() *InterconnectSpec {
-	if in == nil {
-		return nil
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OpenStackClient) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
 	}
-	out := new(InterconnectSpec)
-	in.DeepCopyInto(out)
-	return out
+	return nil
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *KeystoneSpec) DeepCopyInto(out *KeystoneSpec) {
+func (in *OpenStackClientList) DeepCopyInto(out *OpenStackClientList) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OpenStackClient, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeystoneSpec.
-func (in *KeystoneSpec) DeepCopy() *KeystoneSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackClientList.
+func (in *OpenStackClientList) DeepCopy() *OpenStackClientList {
 	if in == nil {
 		return nil
 	}
-	out := new(KeystoneSpec)
+	out := new(OpenStackClientList)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OpenStackClientList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NeutronSpec) DeepCopyInto(out *NeutronSpec) {
+func (in *OpenStackClientSpec) DeepCopyInto(out *OpenStackClientSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeutronSpec.
-func (in *NeutronSpec) DeepCopy() *NeutronSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackClientSpec.
+func (in *OpenStackClientSpec) DeepCopy() *OpenStackClientSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NeutronSpec)
+	out := new(OpenStackClientSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NovaSpec) DeepCopyInto(out *NovaSpec) {
+func (in *OpenStackClientStatus) DeepCopyInto(out *OpenStackClientStatus) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NovaSpec.
-func (in *NovaSpec) DeepCopy() *NovaSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackClientStatus.
+func (in *OpenStackClientStatus) DeepCopy() *OpenStackClientStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(NovaSpec)
+	out := new(OpenStackClientStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OpenStackClient) DeepCopyInto(out *OpenStackClient) {
+func (in *OpenStackCommand) DeepCopyInto(out *OpenStackCommand) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackClient.
-func (in *OpenStackClient) DeepCopy() *OpenStackClient {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackCommand.
+func (in *OpenStackCommand) DeepCopy() *OpenStackCommand {
 	if in == nil {
 		return nil
 	}
-	out := new(OpenStackClient)
+	out := new(OpenStackCommand)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *OpenStackClient) DeepCopyObject() runtime.Object {
+func (in *OpenStackCommand) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -238,31 +1055,31 @@ func (in *OpenStackClient) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OpenStackClientList) DeepCopyInto(out *OpenStackClientList) {
+func (in *OpenStackCommandList) DeepCopyInto(out *OpenStackCommandList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]OpenStackClient, len(*in))
+		*out = make([]OpenStackCommand, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackClientList.
-func (in *OpenStackClientList) DeepCopy() *OpenStackClientList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackCommandList.
+func (in *OpenStackCommandList) DeepCopy() *OpenStackCommandList {
 	if in == nil {
 		return nil
 	}
-	out := new(OpenStackClientList)
+	out := new(OpenStackCommandList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *OpenStackClientList) DeepCopyObject() runtime.Object {
+func (in *OpenStackCommandList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -270,31 +1087,98 @@ func (in *OpenStackClientList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OpenStackClientSpec) DeepCopyInto(out *OpenStackClientSpec) {
+func (in *OpenStackCommandSpec) DeepCopyInto(out *OpenStackCommandSpec) {
 	*out = *in
+	if in.Commands != nil {
+		in, out := &in.Commands, &out.Commands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BackoffLimit != nil {
+		in, out := &in.BackoffLimit, &out.BackoffLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.TTLSecondsAfterFinished != nil {
+		in, out := &in.TTLSecondsAfterFinished, &out.TTLSecondsAfterFinished
+		*out = new(int32)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackClientSpec.
-func (in *OpenStackClientSpec) DeepCopy() *OpenStackClientSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackCommandSpec.
+func (in *OpenStackCommandSpec) DeepCopy() *OpenStackCommandSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(OpenStackClientSpec)
+	out := new(OpenStackCommandSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OpenStackClientStatus) DeepCopyInto(out *OpenStackClientStatus) {
+func (in *OpenStackCommandStatus) DeepCopyInto(out *OpenStackCommandStatus) {
 	*out = *in
+	if in.ExitCode != nil {
+		in, out := &in.ExitCode, &out.ExitCode
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackClientStatus.
-func (in *OpenStackClientStatus) DeepCopy() *OpenStackClientStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackCommandStatus.
+func (in *OpenStackCommandStatus) DeepCopy() *OpenStackCommandStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(OpenStackClientStatus)
+	out := new(OpenStackCommandStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OverrideSpec) DeepCopyInto(out *OverrideSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OverrideSpec.
+func (in *OverrideSpec) DeepCopy() *OverrideSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OverrideSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PasswordRotationStatus) DeepCopyInto(out *PasswordRotationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRotationTime != nil {
+		in, out := &in.LastRotationTime, &out.LastRotationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PasswordRotationStatus.
+func (in *PasswordRotationStatus) DeepCopy() *PasswordRotationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PasswordRotationStatus)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -302,6 +1186,10 @@ func (in *OpenStackClientStatus) DeepCopy() *OpenStackClientStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlacementSpec) DeepCopyInto(out *PlacementSpec) {
 	*out = *in
+	out.Endpoint = in.Endpoint
+	in.Resources.DeepCopyInto(&out.Resources)
+	in.PodPlacement.DeepCopyInto(&out.PodPlacement)
+	in.ServiceConfigSpec.DeepCopyInto(&out.ServiceConfigSpec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementSpec.
@@ -313,3 +1201,101 @@ func (in *PlacementSpec) DeepCopy() *PlacementSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetSpec) DeepCopyInto(out *PodDisruptionBudgetSpec) {
+	*out = *in
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetSpec.
+func (in *PodDisruptionBudgetSpec) DeepCopy() *PodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodPlacementSpec) DeepCopyInto(out *PodPlacementSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodPlacementSpec.
+func (in *PodPlacementSpec) DeepCopy() *PodPlacementSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodPlacementSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Target) DeepCopyInto(out *S3Target) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Target.
+func (in *S3Target) DeepCopy() *S3Target {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Target)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceConfigSpec) DeepCopyInto(out *ServiceConfigSpec) {
+	*out = *in
+	if in.DefaultConfigOverwrite != nil {
+		in, out := &in.DefaultConfigOverwrite, &out.DefaultConfigOverwrite
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceConfigSpec.
+func (in *ServiceConfigSpec) DeepCopy() *ServiceConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,28 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bindata embeds the manifest templates rendered by the operator
+package bindata
+
+import "embed"
+
+// Manifests - the manifest templates, one directory per service. The whole
+// directories get embedded, so RenderDir finds the same manifests as with
+// --bindata-dir, including subdirectories and json files. New directories
+// have to be added here.
+//
+//go:embed cinder exposure glance interconnect keystone mariadb neutron nova pdb placement
+var Manifests embed.FS
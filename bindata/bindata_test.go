@@ -0,0 +1,55 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bindata
+
+import (
+	"io/fs"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// TestManifestsEmbedded checks that every file of the service directories is
+// embedded
+func TestManifestsEmbedded(t *testing.T) {
+	expected, err := listFiles(os.DirFS("."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded, err := listFiles(Manifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(embedded, expected) {
+		t.Errorf("embedded manifests %v, expected %v", embedded, expected)
+	}
+}
+
+func listFiles(fsys fs.FS) ([]string, error) {
+	files := []string{}
+	err := fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// the go files are no manifests
+		if !entry.IsDir() && path != "bindata.go" && path != "bindata_test.go" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,139 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * Copyright 2020 Red Hat, Inc.
+ *
+ */
+
+// render prints the manifests the operator would apply for a ControlPlane
+// without talking to a cluster. The PodDisruptionBudgets and load balancer
+// services need the pod selectors of the child operator services, so they
+// aren't printed.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/ghodss/yaml"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	"github.com/openstack-k8s-operators/openstack-cluster-operator/controllers"
+)
+
+const defaultNamespace = "openstack"
+
+var (
+	controlPlaneFile = flag.String("controlplane", "", "ControlPlane YAML file to render, - for stdin")
+	namespace        = flag.String("namespace", "", "Namespace to render into, defaults to the namespace of the ControlPlane or "+defaultNamespace)
+	bindataDir       = flag.String("bindata-dir", "", "Render the manifests from this directory instead of the ones embedded into the binary")
+	diffFile         = flag.String("diff", "", "Print the differences to the output of a previous run instead of the manifests, exits with 1 if there are any")
+)
+
+func main() {
+	flag.Parse()
+
+	if *controlPlaneFile == "" {
+		log.Fatal("--controlplane is required")
+	}
+
+	instance, err := readControlPlane(*controlPlaneFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *namespace != "" {
+		instance.Namespace = *namespace
+	}
+	if instance.Namespace == "" {
+		instance.Namespace = defaultNamespace
+	}
+
+	if *bindataDir != "" {
+		controllers.Manifests = os.DirFS(*bindataDir)
+	}
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(controlplanev1beta1.AddToScheme(scheme))
+
+	objs, err := controllers.RenderControlPlane(context.TODO(), nil, instance, scheme)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := bytes.Buffer{}
+	for _, obj := range objs {
+		manifest, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			log.Fatalf("failed to marshal %s %s: %v", obj.GetKind(), obj.GetName(), err)
+		}
+		out.WriteString("---\n")
+		out.Write(manifest)
+	}
+
+	if *diffFile == "" {
+		fmt.Print(out.String())
+		return
+	}
+
+	previous, err := ioutil.ReadFile(*diffFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(previous)),
+		B:        difflib.SplitLines(out.String()),
+		FromFile: *diffFile,
+		ToFile:   "rendered",
+		Context:  3,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if diff != "" {
+		fmt.Print(diff)
+		os.Exit(1)
+	}
+}
+
+// readControlPlane reads the ControlPlane from the file, or from stdin if the path is -
+func readControlPlane(path string) (*controlplanev1beta1.ControlPlane, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	instance := &controlplanev1beta1.ControlPlane{}
+	if err := yaml.Unmarshal(content, instance); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	if instance.Kind != "ControlPlane" {
+		return nil, fmt.Errorf("%s is not a ControlPlane but a %q", path, instance.Kind)
+	}
+	return instance, nil
+}
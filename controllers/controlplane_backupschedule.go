@@ -0,0 +1,256 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+// label on the jobs of the backup CronJob with the name of the ControlPlane
+const backupScheduleLabelSelector = "controlplane.openstack.org/backup-schedule"
+
+// backupScheduleKinds - kinds rendered for the scheduled backups
+var backupScheduleKinds = []schema.GroupVersionKind{
+	batchv1beta1.SchemeGroupVersion.WithKind("CronJob"),
+}
+
+// backupResult - written to the termination log by the last container of a
+// scheduled backup
+type backupResult struct {
+	Location string `json:"location"`
+	Size     int64  `json:"size"`
+}
+
+// validateBackupSchedule checks the cron schedule and the target of the
+// scheduled backups. Only the number of schedule fields is checked, the
+// CronJob controller reports invalid values.
+func validateBackupSchedule(schedule *controlplanev1beta1.BackupScheduleSpec) error {
+	if !strings.HasPrefix(schedule.Schedule, "@") && len(strings.Fields(schedule.Schedule)) != 5 {
+		return fmt.Errorf("schedule %q is neither a cron expression with 5 fields nor a predefined schedule", schedule.Schedule)
+	}
+	if schedule.Retention.Count < 0 {
+		return fmt.Errorf("retention count must not be negative")
+	}
+	return validateBackupTarget(&schedule.Target)
+}
+
+func getBackupCronJobName(instance *controlplanev1beta1.ControlPlane) string {
+	return instance.Name + "-backup"
+}
+
+// getBackupCronJob returns the CronJob dumping all databases of the MariaDB
+// on the schedule and deleting the dumps outside of the retention. The dumps
+// are named <controlplane>-<UTC timestamp>.sql.gz, so they sort by age. The
+// CronJob is suspended while a restore quiesces the ControlPlane.
+func getBackupCronJob(instance *controlplanev1beta1.ControlPlane) (*uns.Unstructured, error) {
+	schedule := instance.Spec.BackupSchedule
+	prefix := instance.Name + "-"
+	secret := getServiceSecrets(&instance.Spec).MariaDB
+
+	script := []string{
+		"set -o pipefail",
+		fmt.Sprintf("name=%s$(date -u +%%Y%%m%%d%%H%%M%%S).sql.gz", prefix),
+		fmt.Sprintf("mysqldump -h %s -u root --all-databases --single-transaction --routines --events | gzip > %s/$name.tmp", mariadbHostname, backupMountPath),
+		fmt.Sprintf("mv %s/$name.tmp %s/$name", backupMountPath, backupMountPath),
+	}
+
+	job := getBackupRestoreJob(getBackupCronJobName(instance), instance.Namespace, &schedule.Target)
+	podSpec := &job.Spec.Template.Spec
+	if s3 := schedule.Target.S3; s3 != nil {
+		// the dump gets uploaded and pruned by the AWS CLI container
+		url := "s3://" + path.Join(s3.Bucket, s3.Prefix)
+		aws := "aws --endpoint-url " + shellQuote(s3.Endpoint)
+		script = append(script, fmt.Sprintf("echo \"$name\" > %s/name", backupMountPath))
+		podSpec.InitContainers = []corev1.Container{getMariaDBContainer("dump", secret, script)}
+		podSpec.Containers = []corev1.Container{
+			getS3Container("upload", s3, strings.Join([]string{
+				"set -e -o pipefail",
+				getPruneFunction(prefix, &schedule.Retention),
+				fmt.Sprintf("name=$(cat %s/name)", backupMountPath),
+				fmt.Sprintf("url=%s", shellQuote(url)),
+				fmt.Sprintf("%s s3 cp %s/$name $url/$name", aws, backupMountPath),
+				fmt.Sprintf("%s s3 ls $url/%s | while read -r _ _ _ key; do echo \"$key\"; done | prune | while read -r old; do %s s3 rm \"$url/$old\"; done", aws, prefix, aws),
+				fmt.Sprintf("printf '{\"location\":\"%%s\",\"size\":%%d}' \"$url/$name\" \"$(stat -c %%s %s/$name)\" > %s", backupMountPath, corev1.TerminationMessagePathDefault),
+			}, "\n")),
+		}
+	} else {
+		script = append(script,
+			getPruneFunction(prefix, &schedule.Retention),
+			"cd "+backupMountPath,
+			fmt.Sprintf("ls -1 | grep '^%s.*\\.sql\\.gz$' | prune | xargs -r rm -f --", prefix),
+			fmt.Sprintf("printf '{\"location\":\"%%s\",\"size\":%%d}' \"$name\" \"$(stat -c %%s $name)\" > %s", corev1.TerminationMessagePathDefault),
+		)
+		podSpec.Containers = []corev1.Container{getMariaDBContainer("dump", secret, script)}
+	}
+	job.Spec.Template.Labels = map[string]string{
+		"app":                       "controlplanebackup",
+		backupScheduleLabelSelector: instance.Name,
+	}
+
+	suspend := instance.GetAnnotations()[quiesceAnnotation] != ""
+	cronJob := &batchv1beta1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: batchv1beta1.SchemeGroupVersion.String(),
+			Kind:       "CronJob",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getBackupCronJobName(instance),
+			Namespace: instance.Namespace,
+		},
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:          schedule.Schedule,
+			ConcurrencyPolicy: batchv1beta1.ForbidConcurrent,
+			Suspend:           &suspend,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						backupScheduleLabelSelector: instance.Name,
+					},
+				},
+				Spec: job.Spec,
+			},
+		},
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cronJob)
+	if err != nil {
+		return nil, err
+	}
+	delete(obj, "status")
+	return &uns.Unstructured{Object: obj}, nil
+}
+
+// getPruneFunction returns a bash function reading dump names from stdin and
+// printing the ones outside of the retention. The newest dump is always kept.
+func getPruneFunction(prefix string, retention *controlplanev1beta1.BackupRetention) string {
+	cutoff := ""
+	if retention.MaxAge != nil {
+		cutoff = fmt.Sprintf("$(date -u -d @$(( $(date +%%s) - %d )) +%%Y%%m%%d%%H%%M%%S)", int64(retention.MaxAge.Seconds()))
+	}
+	return strings.Join([]string{
+		"prune() {",
+		"  mapfile -t names < <(sort)",
+		fmt.Sprintf("  cutoff=%s", cutoff),
+		"  for i in \"${!names[@]}\"; do",
+		"    newer=$(( ${#names[@]} - i - 1 ))",
+		"    [ $newer -eq 0 ] && continue",
+		fmt.Sprintf("    stamp=${names[$i]#%s}; stamp=${stamp%%.sql.gz}", prefix),
+		fmt.Sprintf("    if { [ %d -gt 0 ] && [ $newer -ge %d ]; } || [[ -n \"$cutoff\" && \"$stamp\" < \"$cutoff\" ]]; then", retention.Count, retention.Count),
+		"      echo \"${names[$i]}\"",
+		"    fi",
+		"  done",
+		"}",
+	}, "\n")
+}
+
+// deleteUnrenderedBackupSchedule deletes the backup CronJob of the
+// ControlPlane once the backup schedule got removed from the spec
+func deleteUnrenderedBackupSchedule(ctx context.Context, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane, objs []*uns.Unstructured) error {
+	names := map[string]bool{getBackupCronJobName(instance): true}
+	return deleteUnrenderedObjects(ctx, c, log, instance, objs, backupScheduleKinds, names)
+}
+
+// getLastScheduledBackup returns the latest succeeded job of the backup
+// CronJob and the result it reported, nil if there is none
+func getLastScheduledBackup(ctx context.Context, c client.Client, instance *controlplanev1beta1.ControlPlane) (*batchv1.Job, *backupResult, error) {
+	jobs := &batchv1.JobList{}
+	err := c.List(ctx, jobs, client.InNamespace(instance.Namespace), client.MatchingLabels{backupScheduleLabelSelector: instance.Name})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var last *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if phase, _ := getJobPhase(job); phase != controlplanev1beta1.CommandPhaseSucceeded {
+			continue
+		}
+		if last == nil || last.Status.CompletionTime.Before(job.Status.CompletionTime) {
+			last = job
+		}
+	}
+	if last == nil {
+		return nil, nil, nil
+	}
+
+	pods := &corev1.PodList{}
+	err = c.List(ctx, pods, client.InNamespace(last.Namespace), client.MatchingLabels{"job-name": last.Name})
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.ContainerStatuses {
+			t := cs.State.Terminated
+			if t == nil || t.ExitCode != 0 {
+				continue
+			}
+			result := &backupResult{}
+			if err := json.Unmarshal([]byte(t.Message), result); err == nil && result.Location != "" {
+				return last, result, nil
+			}
+		}
+	}
+	return last, nil, nil
+}
+
+// setBackupStatus records the last successful scheduled backup
+func setBackupStatus(ctx context.Context, c client.Client, instance *controlplanev1beta1.ControlPlane) error {
+	if instance.Spec.BackupSchedule == nil {
+		return nil
+	}
+	job, result, err := getLastScheduledBackup(ctx, c, instance)
+	if err != nil || job == nil || result == nil {
+		return err
+	}
+	// jobs outside of the history limit are gone, keep what was recorded
+	if instance.Status.LastBackupTime != nil && !instance.Status.LastBackupTime.Before(job.Status.CompletionTime) {
+		return nil
+	}
+	instance.Status.LastBackupTime = job.Status.CompletionTime.DeepCopy()
+	instance.Status.LastBackupSize = result.Size
+	instance.Status.LastBackupLocation = result.Location
+	return nil
+}
+
+// backupJobToControlPlane maps the jobs of the backup CronJob to their ControlPlane
+var backupJobToControlPlane = handler.ToRequestsFunc(func(obj handler.MapObject) []reconcile.Request {
+	name, ok := obj.Meta.GetLabels()[backupScheduleLabelSelector]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: client.ObjectKey{Name: name, Namespace: obj.Meta.GetNamespace()}},
+	}
+})
@@ -0,0 +1,204 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+func TestValidateBackupSchedule(t *testing.T) {
+	pvc := controlplanev1beta1.BackupTarget{PersistentVolumeClaim: "backups"}
+	tests := []struct {
+		name     string
+		schedule controlplanev1beta1.BackupScheduleSpec
+		valid    bool
+	}{
+		{
+			name:     "cron expression",
+			schedule: controlplanev1beta1.BackupScheduleSpec{Schedule: "0 2 * * *", Target: pvc},
+			valid:    true,
+		},
+		{
+			name:     "predefined schedule",
+			schedule: controlplanev1beta1.BackupScheduleSpec{Schedule: "@daily", Target: pvc},
+			valid:    true,
+		},
+		{
+			name:     "cron expression with seconds",
+			schedule: controlplanev1beta1.BackupScheduleSpec{Schedule: "0 0 2 * * *", Target: pvc},
+		},
+		{
+			name:     "empty schedule",
+			schedule: controlplanev1beta1.BackupScheduleSpec{Target: pvc},
+		},
+		{
+			name: "negative retention count",
+			schedule: controlplanev1beta1.BackupScheduleSpec{Schedule: "@daily", Target: pvc,
+				Retention: controlplanev1beta1.BackupRetention{Count: -1}},
+		},
+		{
+			name:     "no target",
+			schedule: controlplanev1beta1.BackupScheduleSpec{Schedule: "@daily"},
+		},
+		{
+			name: "two targets",
+			schedule: controlplanev1beta1.BackupScheduleSpec{Schedule: "@daily",
+				Target: controlplanev1beta1.BackupTarget{PersistentVolumeClaim: "backups", S3: &controlplanev1beta1.S3Target{Bucket: "backups"}}},
+		},
+	}
+	for _, test := range tests {
+		err := validateBackupSchedule(&test.schedule)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestGetPruneFunction(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	stamp := func(age time.Duration) string {
+		return "cp-" + time.Now().UTC().Add(-age).Format("20060102150405") + ".sql.gz"
+	}
+	day := 24 * time.Hour
+	// unsorted, the function sorts the names by their timestamp
+	names := []string{stamp(2 * day), stamp(10 * day), stamp(0), stamp(5 * day), stamp(1 * day)}
+
+	tests := []struct {
+		name      string
+		retention controlplanev1beta1.BackupRetention
+		pruned    []string
+	}{
+		{
+			name:   "keep all",
+			pruned: nil,
+		},
+		{
+			name:      "count",
+			retention: controlplanev1beta1.BackupRetention{Count: 2},
+			pruned:    []string{names[1], names[3], names[0]},
+		},
+		{
+			name:      "max age",
+			retention: controlplanev1beta1.BackupRetention{MaxAge: &metav1.Duration{Duration: 3 * day}},
+			pruned:    []string{names[1], names[3]},
+		},
+		{
+			name:      "count and max age",
+			retention: controlplanev1beta1.BackupRetention{Count: 4, MaxAge: &metav1.Duration{Duration: 7 * day}},
+			pruned:    []string{names[1]},
+		},
+		{
+			name:      "newest dump is kept",
+			retention: controlplanev1beta1.BackupRetention{MaxAge: &metav1.Duration{Duration: time.Second}},
+			pruned:    []string{names[1], names[3], names[0], names[4]},
+		},
+	}
+	for _, test := range tests {
+		script := getPruneFunction("cp-", &test.retention) + "\nprune"
+		cmd := exec.Command("bash", "-c", script)
+		cmd.Stdin = strings.NewReader(strings.Join(names, "\n") + "\n")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		pruned := strings.Fields(string(out))
+		if len(pruned) == 0 {
+			pruned = nil
+		}
+		if !reflect.DeepEqual(pruned, test.pruned) {
+			t.Errorf("%s: expected %v to be pruned, got %v", test.name, test.pruned, pruned)
+		}
+	}
+}
+
+func TestGetBackupCronJobSuspend(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cp", Namespace: "openstack"}}
+	instance.Spec.BackupSchedule = &controlplanev1beta1.BackupScheduleSpec{
+		Schedule: "@daily",
+		Target:   controlplanev1beta1.BackupTarget{PersistentVolumeClaim: "backups"},
+	}
+	for restore, suspend := range map[string]bool{"": false, "restore": true} {
+		instance.SetAnnotations(map[string]string{quiesceAnnotation: restore})
+		cronJob, err := getBackupCronJob(instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok, _ := uns.NestedBool(cronJob.Object, "spec", "suspend")
+		if !ok || got != suspend {
+			t.Errorf("quiesced by %q: expected suspend %v, got %v", restore, suspend, got)
+		}
+	}
+}
+
+func TestDeleteUnrenderedBackupSchedule(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cp", Namespace: "openstack", UID: "uid"}}
+	cronJob := func(name string) *batchv1beta1.CronJob {
+		return &batchv1beta1.CronJob{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "openstack",
+			Labels:    map[string]string{ownerUIDLabelSelector: "uid"},
+		}}
+	}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, cronJob("cp-backup"), cronJob("other"))
+
+	// still scheduled
+	instance.Spec.BackupSchedule = &controlplanev1beta1.BackupScheduleSpec{
+		Schedule: "@daily",
+		Target:   controlplanev1beta1.BackupTarget{PersistentVolumeClaim: "backups"},
+	}
+	rendered, err := getBackupCronJob(instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteUnrenderedBackupSchedule(context.TODO(), c, logf.NullLogger{}, instance, []*uns.Unstructured{rendered}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cp-backup", Namespace: "openstack"}, &batchv1beta1.CronJob{}); err != nil {
+		t.Errorf("expected the rendered CronJob to be kept: %v", err)
+	}
+
+	// schedule removed
+	if err := deleteUnrenderedBackupSchedule(context.TODO(), c, logf.NullLogger{}, instance, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "cp-backup", Namespace: "openstack"}, &batchv1beta1.CronJob{}); err == nil {
+		t.Errorf("expected the CronJob of the removed schedule to be deleted")
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "other", Namespace: "openstack"}, &batchv1beta1.CronJob{}); err != nil {
+		t.Errorf("expected other CronJobs to be kept: %v", err)
+	}
+}
@@ -18,23 +18,53 @@ package controllers
 
 import (
 	"context"
-	"path/filepath"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	"github.com/openstack-k8s-operators/openstack-cluster-operator/bindata"
 	bindatautil "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/bindata_util"
+	util "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/util"
 )
 
-// ManifestPath - bindata path
-var ManifestPath = "./bindata"
+// Manifests - bindata templates, embedded into the binary unless overridden
+var Manifests fs.FS = bindata.Manifests
+
+// maxParallelApply - number of objects of a rollout phase applied concurrently
+const maxParallelApply = 8
+
+// reasons of the events recorded on the reconciled objects
+const (
+	eventReasonCreated     = "Created"
+	eventReasonUpdated     = "Updated"
+	eventReasonApplyFailed = "ApplyFailed"
+	eventReasonInvalidSpec = "InvalidSpec"
+	eventReasonSecretError = "SecretError"
+)
 
 const (
 	ownerUIDLabelSelector       = "controlplane.openstack.org/uid"
@@ -45,17 +75,28 @@ const (
 // ControlPlaneReconciler reconciles a ControlPlane object
 type ControlPlaneReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	// reads from the API server, the cache of the client only holds the
+	// objects of the watched namespace
+	APIReader client.Reader
+	Log       logr.Logger
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=controlplane.openstack.org,resources=controlplanes,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=controlplane.openstack.org,resources=controlplanes/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=deployments;replicasets;statefulsets,verbs=get;list;watch
 
 // Reconcile - controleplane api
 func (r *ControlPlaneReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	_ = context.Background()
-	_ = r.Log.WithValues("controlplane", req.NamespacedName)
+	log := r.Log.WithValues("controlplane", req.NamespacedName)
 
 	// Fetch the ControlPlane instance
 	instance := &controlplanev1beta1.ControlPlane{}
@@ -63,144 +104,571 @@ func (r *ControlPlaneReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 	if err != nil {
 		if k8s_errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected.
-			// For additional cleanup logic use finalizers. Return and don't requeue.
+			// The rendered objects got deleted by the teardown before the finalizer was released.
+			// Return and don't requeue.
+			forgetControlPlaneMetrics(req.Namespace, req.Name)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return ctrl.Result{}, err
 	}
-	setDefaults(instance)
 
-	data, err := getRenderData(context.TODO(), r.Client, instance)
-	if err != nil {
-		return ctrl.Result{}, err
+	// Delete the rendered objects before releasing the ControlPlane
+	if !instance.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(instance, controlPlaneFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		// rendered objects can live in other namespaces than the ControlPlane
+		done, err := teardown(context.TODO(), r.getUncachedClient(), log, instance)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return ctrl.Result{RequeueAfter: rolloutMinBackoff}, nil
+		}
+		controllerutil.RemoveFinalizer(instance, controlPlaneFinalizer)
+		if err := r.Client.Update(context.TODO(), instance); err != nil {
+			return ctrl.Result{}, err
+		}
+		forgetControlPlaneMetrics(instance.Namespace, instance.Name)
+		log.Info("Teardown completed")
+		return ctrl.Result{}, nil
+	}
+	if !controllerutil.ContainsFinalizer(instance, controlPlaneFinalizer) {
+		controllerutil.AddFinalizer(instance, controlPlaneFinalizer)
+		if err := r.Client.Update(context.TODO(), instance); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
-	objs := []*uns.Unstructured{}
+	// Scale the API services back up if the restore they were scaled down
+	// for finished or got deleted
+	if restore := instance.GetAnnotations()[quiesceAnnotation]; restore != "" {
+		active, err := isRestoreActive(context.TODO(), r.Client, instance.Namespace, restore)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !active {
+			log.Info("Removing quiesce annotation of inactive restore", "ControlPlaneRestore", restore)
+			if err := setQuiesceAnnotation(r.Client, instance, ""); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
 
-	// Generate the MariaDB objects
-	manifests, err := bindatautil.RenderDir(filepath.Join(ManifestPath, "mariadb"), &data)
+	objs, err := RenderControlPlane(context.TODO(), r.Client, instance, r.Scheme)
 	if err != nil {
-		ctrl.Log.Error(err, "Failed to render mariadb manifests : %v")
+		log.Error(err, "Failed to render control plane")
+		renderErrors.WithLabelValues(trackLabels(renderErrors, instance.Namespace, instance.Name)...).Inc()
+		r.Recorder.Event(instance, corev1.EventTypeWarning, eventReasonInvalidSpec, err.Error())
 		return ctrl.Result{}, err
 	}
-	objs = append(objs, manifests...)
 
-	// Generate the AMQ Interconnect objects
-	manifests, err = bindatautil.RenderDir(filepath.Join(ManifestPath, "interconnect"), &data)
+	oldStatus := instance.Status.DeepCopy()
+
+	// Nothing gets applied until the referenced secrets have the keys the
+	// services need. Secrets aren't watched, so check them again later.
+	secretErrors, err := validateSecretRefs(context.TODO(), r.Client, instance)
 	if err != nil {
-		ctrl.Log.Error(err, "Failed to render interconnect manifests : %v")
 		return ctrl.Result{}, err
 	}
-	objs = append(objs, manifests...)
+	instance.Status.SecretErrors = nil
+	if len(secretErrors) > 0 {
+		log.Info("Referenced secrets are not usable", "Problems", secretErrors)
+		r.Recorder.Event(instance, corev1.EventTypeWarning, eventReasonSecretError, strings.Join(secretErrors, "; "))
+		instance.Status.SecretErrors = secretErrors
+		instance.Status.Ready = false
+		instance.Status.Quiesced = false
+		if !reflect.DeepEqual(oldStatus, &instance.Status) {
+			if err := r.Client.Status().Update(context.TODO(), instance); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: rolloutMaxBackoff}, nil
+	}
 
-	// Generate the Keystone objects
-	manifests, err = bindatautil.RenderDir(filepath.Join(ManifestPath, "keystone"), &data)
-	if err != nil {
-		ctrl.Log.Error(err, "Failed to render keystone manifests : %v")
+	oref := metav1.NewControllerRef(instance, instance.GroupVersionKind())
+	labelSelector := map[string]string{
+		ownerUIDLabelSelector:       string(instance.UID),
+		ownerNameSpaceLabelSelector: instance.Namespace,
+		ownerNameLabelSelector:      instance.Name,
+	}
+	for _, obj := range objs {
+		// Set owner reference on objects in the same namespace as the operator
+		if obj.GetNamespace() == instance.Namespace {
+			obj.SetOwnerReferences([]metav1.OwnerReference{*oref})
+		}
+		// merge owner ref label into labels on the objects
+		obj.SetLabels(labels.Merge(obj.GetLabels(), labelSelector))
+	}
+
+	// Apply the objects to the cluster phase by phase, a phase only gets
+	// applied once the objects of the previous phases are ready. The objects
+	// of a phase get applied concurrently and a failing object doesn't stop
+	// the others.
+	var requeueAfter time.Duration
+	applyErrs := []error{}
+	assumedReady := []string{}
+	instance.Status.ApplyErrors = nil
+	for i, phase := range rolloutPhases {
+		phaseObjs := getPhaseObjects(objs, phase)
+		for _, result := range bindatautil.ApplyObjects(context.TODO(), r.Client, log, phaseObjs, maxParallelApply) {
+			obj := result.Object
+			recordApplyResult(instance, result)
+			switch {
+			case result.Err != nil:
+				log.Error(result.Err, "Failed to apply object", "Kind", obj.GetKind(), "Name", obj.GetName())
+				r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventReasonApplyFailed, "Failed to apply %s %s: %v", obj.GetKind(), obj.GetName(), result.Err)
+				applyErrs = append(applyErrs, result.Err)
+				instance.Status.ApplyErrors = append(instance.Status.ApplyErrors,
+					fmt.Sprintf("%s/%s: %v", obj.GetKind(), obj.GetName(), result.Err))
+			case result.Operation == controllerutil.OperationResultCreated:
+				r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonCreated, "Created %s %s", obj.GetKind(), obj.GetName())
+			case result.Operation == controllerutil.OperationResultUpdated:
+				r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonUpdated, "Updated %s %s", obj.GetKind(), obj.GetName())
+			}
+		}
+
+		notReady, unknown, err := getNotReadyObjects(context.TODO(), r.Client, phaseObjs)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		// objects whose readiness is unknown hold the phase back until the
+		// timeout, earlier phases passed it already
+		if len(unknown) > 0 {
+			waited := time.Duration(0)
+			if instance.Status.RolloutPhase == i+1 && instance.Status.RolloutPhaseStartTime != nil {
+				waited = time.Since(instance.Status.RolloutPhaseStartTime.Time)
+			}
+			if instance.Status.RolloutPhase > i+1 || waited >= rolloutUnknownReadinessTimeout {
+				assumedReady = append(assumedReady, unknown...)
+			} else {
+				notReady = append(notReady, unknown...)
+			}
+		}
+		setRolloutStatus(instance, i+1, phase, notReady)
+		if len(notReady) > 0 || len(applyErrs) > 0 {
+			requeueAfter = getRolloutBackoff(time.Since(instance.Status.RolloutPhaseStartTime.Time))
+			log.Info("Waiting for rollout phase to become ready", "Phase", i+1, "WaitingFor", notReady, "RequeueAfter", requeueAfter)
+			break
+		}
+	}
+	instance.Status.AssumedReady = nil
+	if len(assumedReady) > 0 {
+		log.Info("Assuming objects without readiness status are ready", "Objects", assumedReady)
+		instance.Status.AssumedReady = assumedReady
+	}
+	// Remove the exposure objects of a previous exposure type or hostname
+	if err := deleteUnrenderedExposures(context.TODO(), r.Client, log, instance, objs); err != nil {
+		return ctrl.Result{}, err
+	}
+	// Remove the budgets of the services scaled down to a single replica
+	if err := deleteUnrenderedDisruptionBudgets(context.TODO(), r.Client, log, instance, objs); err != nil {
+		return ctrl.Result{}, err
+	}
+	// Remove the secrets created before a service referenced an existing one
+	if err := deleteReplacedSecrets(context.TODO(), r.Client, log, instance, objs); err != nil {
+		return ctrl.Result{}, err
+	}
+	// Remove the backup CronJob once the backup schedule got removed
+	if err := deleteUnrenderedBackupSchedule(context.TODO(), r.Client, log, instance, objs); err != nil {
 		return ctrl.Result{}, err
 	}
-	objs = append(objs, manifests...)
 
-	// Generate the Glance objects
-	manifests, err = bindatautil.RenderDir(filepath.Join(ManifestPath, "glance"), &data)
-	if err != nil {
-		ctrl.Log.Error(err, "Failed to render glance manifests : %v")
+	instance.Status.Ready = requeueAfter == 0
+	instance.Status.Quiesced = false
+	if instance.Status.Ready && instance.GetAnnotations()[quiesceAnnotation] != "" {
+		running, err := getRunningServicePods(context.TODO(), r.Client, objs)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		instance.Status.Quiesced = len(running) == 0
+		if !instance.Status.Quiesced {
+			log.Info("Waiting for the pods of the API services to terminate", "Pods", running)
+			requeueAfter = rolloutMinBackoff
+		}
+	}
+	recordRolloutStatus(instance, oldStatus)
+	if err := recordSecretAges(context.TODO(), r.Client, instance, objs); err != nil {
 		return ctrl.Result{}, err
 	}
-	objs = append(objs, manifests...)
 
-	// Generate the Placement objects
-	manifests, err = bindatautil.RenderDir(filepath.Join(ManifestPath, "placement"), &data)
+	// Report the external URLs of the service APIs
+	endpoints, err := getExternalURLs(context.TODO(), r.Client, instance)
 	if err != nil {
-		ctrl.Log.Error(err, "Failed to render placement manifests : %v")
 		return ctrl.Result{}, err
 	}
-	objs = append(objs, manifests...)
+	instance.Status.Endpoints = endpoints
 
-	// Generate the Neutron objects
-	manifests, err = bindatautil.RenderDir(filepath.Join(ManifestPath, "neutron"), &data)
-	if err != nil {
-		ctrl.Log.Error(err, "Failed to render neutron manifests : %v")
+	if err := setBackupStatus(context.TODO(), r.Client, instance); err != nil {
 		return ctrl.Result{}, err
 	}
-	objs = append(objs, manifests...)
 
-	// Generate the Cinder objects
-	// TODO: how to handle adding additional cinder-volume services using openstack-cluster-operator
-	manifests, err = bindatautil.RenderDir(filepath.Join(ManifestPath, "cinder"), &data)
+	// Rotate the passwords of the services one after the other
+	rotationRequeueAfter, err := reconcilePasswordRotation(context.TODO(), r.Client, log, instance, objs)
 	if err != nil {
-		ctrl.Log.Error(err, "Failed to render cinder manifests : %v")
 		return ctrl.Result{}, err
 	}
-	objs = append(objs, manifests...)
+	if rotationRequeueAfter > 0 && (requeueAfter == 0 || rotationRequeueAfter < requeueAfter) {
+		requeueAfter = rotationRequeueAfter
+	}
+
+	if !reflect.DeepEqual(oldStatus, &instance.Status) {
+		if err := r.Client.Status().Update(context.TODO(), instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if len(applyErrs) > 0 {
+		return ctrl.Result{}, utilerrors.NewAggregate(applyErrs)
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// setRolloutStatus records the rollout phase being applied
+func setRolloutStatus(instance *controlplanev1beta1.ControlPlane, phase int, services []string, notReady []string) {
+	if instance.Status.RolloutPhase != phase || instance.Status.RolloutPhaseStartTime == nil {
+		now := metav1.Now()
+		instance.Status.RolloutPhaseStartTime = &now
+	}
+	instance.Status.RolloutPhase = phase
+	instance.Status.RolloutServices = services
+	instance.Status.WaitingFor = nil
+	if len(notReady) > 0 {
+		instance.Status.WaitingFor = notReady
+	}
+}
+
+// RenderControlPlane defaults and validates the ControlPlane and returns the
+// rendered objects of all services with the overrides applied. Nothing gets
+// applied, so it is also used by the render CLI.
+func RenderControlPlane(ctx context.Context, c client.Client, instance *controlplanev1beta1.ControlPlane, scheme *runtime.Scheme) ([]*uns.Unstructured, error) {
+	setDefaults(instance)
 
-	// Generate the Nova objects
-	// TODO: how to handle adding additional cells using openstack-cluster-operator
-	manifests, err = bindatautil.RenderDir(filepath.Join(ManifestPath, "nova"), &data)
+	if err := validateKeystoneIdentity(instance); err != nil {
+		return nil, fmt.Errorf("invalid keystone identity settings: %v", err)
+	}
+
+	if err := validateServiceConfigs(instance); err != nil {
+		return nil, fmt.Errorf("invalid service config: %v", err)
+	}
+
+	if instance.Spec.BackupSchedule != nil {
+		if err := validateBackupSchedule(instance.Spec.BackupSchedule); err != nil {
+			return nil, fmt.Errorf("invalid backup schedule: %v", err)
+		}
+	}
+
+	values, err := getRenderData(ctx, c, instance)
 	if err != nil {
-		ctrl.Log.Error(err, "Failed to render nova manifests : %v")
-		return ctrl.Result{}, err
+		return nil, err
 	}
-	objs = append(objs, manifests...)
+	if instance.GetAnnotations()[quiesceAnnotation] != "" {
+		quiesceServices(&values.Spec)
+	}
+	data := makeRenderData(values)
 
-	// Apply the objects to the cluster
-	oref := metav1.NewControllerRef(instance, instance.GroupVersionKind())
-	labelSelector := map[string]string{
-		ownerUIDLabelSelector:       string(instance.UID),
-		ownerNameSpaceLabelSelector: instance.Namespace,
-		ownerNameLabelSelector:      instance.Name,
+	objs := []*uns.Unstructured{}
+
+	// Generate the objects of the services
+	for _, dir := range serviceManifestDirs {
+		manifests, err := bindatautil.RenderDir(Manifests, dir, &data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s manifests: %v", dir, err)
+		}
+		setServiceLabel(manifests, dir)
+		objs = append(objs, manifests...)
 	}
-	for _, obj := range objs {
-		// Set owner reference on objects in the same namespace as the operator
-		if obj.GetNamespace() == instance.Namespace {
-			obj.SetOwnerReferences([]metav1.OwnerReference{*oref})
+
+	// Generate the objects exposing the service APIs outside of the cluster
+	if instance.Spec.Exposure.Type != controlplanev1beta1.ExposureNone {
+		for _, svc := range getExposedServices(instance) {
+			svcValues := getExposureRenderData(values, svc)
+			// ingress rules without a host would match every request
+			if instance.Spec.Exposure.Type == controlplanev1beta1.ExposureIngress && svcValues.ExposedService.Hostname == "" {
+				ctrl.Log.Info("Not exposing service without public hostname", "Service", svc.serviceName)
+				continue
+			}
+			// the load balancer selects the API pods with the selector of the
+			// service of the child operator, so it waits for that service
+			if instance.Spec.Exposure.Type == controlplanev1beta1.ExposureLoadBalancer {
+				selector, err := getServiceSelector(ctx, c, instance.Namespace, svc.serviceName)
+				if err != nil {
+					return nil, err
+				}
+				if len(selector) == 0 {
+					ctrl.Log.Info("Not exposing service before its pod selector is known", "Service", svc.serviceName)
+					continue
+				}
+				svcValues.ExposedService.Selector = selector
+			}
+			svcData := makeRenderData(svcValues)
+			manifests, err := bindatautil.RenderDir(Manifests, "exposure", &svcData)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render exposure manifests: %v", err)
+			}
+			setServiceLabel(manifests, strings.ToLower(svc.name))
+			objs = append(objs, manifests...)
 		}
-		// merge owner ref label into labels on the objects
-		obj.SetLabels(labels.Merge(obj.GetLabels(), labelSelector))
-		objs = append(objs, obj)
+	}
 
-		if err := bindatautil.ApplyObject(context.TODO(), r.Client, obj); err != nil {
-			ctrl.Log.Error(err, "Failed to apply objects")
-			return ctrl.Result{}, err
+	// Generate the PodDisruptionBudgets of the API services running more
+	// than one replica, quiesced services don't get one. The budgets select
+	// the API pods like the service of the child operator does, so they
+	// wait for that service.
+	pdbAPIVersion, err := getDisruptionBudgetAPIVersion(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range getDisruptionBudgetServices(&values.Spec) {
+		if svc.replicas <= 1 {
+			continue
+		}
+		selector, err := getServiceSelector(ctx, c, instance.Namespace, svc.serviceName)
+		if err != nil {
+			return nil, err
+		}
+		if len(selector) == 0 {
+			ctrl.Log.Info("Not creating PodDisruptionBudget before the pod selector is known", "Service", svc.serviceName)
+			continue
 		}
+		svcData := makeRenderData(getDisruptionBudgetRenderData(values, svc, pdbAPIVersion, selector))
+		manifests, err := bindatautil.RenderDir(Manifests, "pdb", &svcData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render pdb manifests: %v", err)
+		}
+		setServiceLabel(manifests, svc.name)
+		objs = append(objs, manifests...)
 	}
 
-	return ctrl.Result{}, nil
+	// Generate the CronJob of the scheduled backups, applied with the MariaDB
+	if instance.Spec.BackupSchedule != nil {
+		cronJob, err := getBackupCronJob(instance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate backup CronJob: %v", err)
+		}
+		setServiceLabel([]*uns.Unstructured{cronJob}, "mariadb")
+		objs = append(objs, cronJob)
+	}
+
+	// Patch the rendered objects with the user supplied overrides
+	if err := applyOverrides(instance, objs, scheme); err != nil {
+		return nil, err
+	}
+
+	return objs, nil
+}
+
+// setServiceLabel labels the objects with the service they belong to
+func setServiceLabel(objs []*uns.Unstructured, service string) {
+	for _, obj := range objs {
+		obj.SetLabels(labels.Merge(obj.GetLabels(), map[string]string{serviceLabelSelector: service}))
+	}
+}
+
+// getUncachedClient returns a client reading from the API server, falls back
+// to the client if there is no API reader
+func (r *ControlPlaneReconciler) getUncachedClient() client.Client {
+	if r.APIReader == nil {
+		return r.Client
+	}
+	return client.DelegatingClient{
+		Reader:       r.APIReader,
+		Writer:       r.Client,
+		StatusClient: r.Client,
+	}
 }
 
 // SetupWithManager -
 func (r *ControlPlaneReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&controlplanev1beta1.ControlPlane{}).
+		Owns(&batchv1.Job{}).
+		Watches(&source.Kind{Type: &batchv1.Job{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: backupJobToControlPlane}).
 		Complete(r)
 }
 
-func getRenderData(ctx context.Context, client client.Client, instance *controlplanev1beta1.ControlPlane) (bindatautil.RenderData, error) {
-	data := bindatautil.MakeRenderData()
-	data.Data["KeystoneReplicas"] = instance.Spec.Keystone.Replicas
-	data.Data["GlanceReplicas"] = instance.Spec.Glance.Replicas
-	data.Data["PlacementReplicas"] = instance.Spec.Placement.Replicas
-	data.Data["InterconnectReplicas"] = instance.Spec.Interconnect.Replicas
-	data.Data["NovaAPIReplicas"] = instance.Spec.Nova.NovaAPIReplicas
-	data.Data["NovaConductorReplicas"] = instance.Spec.Nova.NovaConductorReplicas
-	data.Data["NovaMetadataReplicas"] = instance.Spec.Nova.NovaMetadataReplicas
-	data.Data["NovaNoVNCProxyReplicas"] = instance.Spec.Nova.NovaNoVNCProxyReplicas
-	data.Data["NovaSchedulerReplicas"] = instance.Spec.Nova.NovaSchedulerReplicas
-	data.Data["CinderAPIReplicas"] = instance.Spec.Cinder.CinderAPIReplicas
-	data.Data["CinderBackupReplicas"] = instance.Spec.Cinder.CinderBackupReplicas
-	data.Data["CinderSchedulerReplicas"] = instance.Spec.Cinder.CinderSchedulerReplicas
-	data.Data["CinderVolumeReplicas"] = instance.Spec.Cinder.CinderVolumeReplicas
-	data.Data["NeutronAPIReplicas"] = instance.Spec.Neutron.Replicas
-	data.Data["Namespace"] = instance.Namespace
-	data.Data["StorageClass"] = instance.Spec.StorageClass
-	return data, nil
-}
-
 func setDefaults(instance *controlplanev1beta1.ControlPlane) {
 	// required to be greated than 0 by the interconnect operator
 	if instance.Spec.Interconnect.Replicas < 1 {
 		instance.Spec.Interconnect.Replicas = 1
 	}
+	if instance.Spec.Nova.NovaCell1ConductorReplicas == nil {
+		replicas := instance.Spec.Nova.NovaConductorReplicas
+		instance.Spec.Nova.NovaCell1ConductorReplicas = &replicas
+	}
+	if instance.Spec.Region == "" {
+		instance.Spec.Region = "regionOne"
+	}
+	if instance.Spec.Exposure.Type == "" {
+		instance.Spec.Exposure.Type = controlplanev1beta1.ExposureNone
+	}
+	if instance.Spec.DeletionPolicy == "" {
+		instance.Spec.DeletionPolicy = controlplanev1beta1.DeletionPolicyDelete
+	}
+	if instance.Spec.PodDisruptionBudget.MaxUnavailable == nil {
+		maxUnavailable := intstr.FromInt(1)
+		instance.Spec.PodDisruptionBudget.MaxUnavailable = &maxUnavailable
+	}
+	if instance.Spec.Exposure.HostnameTemplate == "" {
+		instance.Spec.Exposure.HostnameTemplate = defaultHostnameTemplate
+	}
+	if instance.Spec.Exposure.TLSTermination == "" {
+		instance.Spec.Exposure.TLSTermination = controlplanev1beta1.TLSTerminationNone
+	}
+	for i := range instance.Spec.Keystone.Identity.Domains {
+		domain := &instance.Spec.Keystone.Identity.Domains[i]
+		if domain.UserObjectClass == "" {
+			domain.UserObjectClass = "inetOrgPerson"
+		}
+		if domain.UserNameAttribute == "" {
+			domain.UserNameAttribute = "uid"
+		}
+		if domain.GroupObjectClass == "" {
+			domain.GroupObjectClass = "groupOfNames"
+		}
+	}
+	for i := range instance.Spec.Keystone.Identity.IdentityProviders {
+		idp := &instance.Spec.Keystone.Identity.IdentityProviders[i]
+		if idp.Domain == "" {
+			idp.Domain = "Default"
+		}
+	}
+	for i := range instance.Spec.Overrides {
+		override := &instance.Spec.Overrides[i]
+		if override.Type == "" {
+			override.Type = controlplanev1beta1.OverrideStrategic
+		}
+	}
+}
+
+// validateKeystoneIdentity checks the keystone domains and identity providers
+// before they get rendered into the keystone config
+func validateKeystoneIdentity(instance *controlplanev1beta1.ControlPlane) error {
+	domains := map[string]bool{}
+	for _, domain := range instance.Spec.Keystone.Identity.Domains {
+		if domains[domain.Name] {
+			return fmt.Errorf("keystone domain %s is defined more than once", domain.Name)
+		}
+		domains[domain.Name] = true
+		if !strings.HasPrefix(domain.URL, "ldap://") && !strings.HasPrefix(domain.URL, "ldaps://") {
+			return fmt.Errorf("keystone domain %s has no ldap:// or ldaps:// URL: %s", domain.Name, domain.URL)
+		}
+	}
+
+	idps := map[string]bool{}
+	for _, idp := range instance.Spec.Keystone.Identity.IdentityProviders {
+		if idps[idp.Name] {
+			return fmt.Errorf("keystone identity provider %s is defined more than once", idp.Name)
+		}
+		idps[idp.Name] = true
+		if !json.Valid([]byte(idp.Mapping)) {
+			return fmt.Errorf("mapping of keystone identity provider %s is not valid JSON", idp.Name)
+		}
+	}
+	return nil
+}
+
+// getServiceConfigs returns the additional config of the OpenStack services,
+// keyed by the prefix of their render data keys
+func getServiceConfigs(instance *controlplanev1beta1.ControlPlane) map[string]controlplanev1beta1.ServiceConfigSpec {
+	return map[string]controlplanev1beta1.ServiceConfigSpec{
+		"Keystone":  instance.Spec.Keystone.ServiceConfigSpec,
+		"Glance":    instance.Spec.Glance.ServiceConfigSpec,
+		"Placement": instance.Spec.Placement.ServiceConfigSpec,
+		"Neutron":   instance.Spec.Neutron.ServiceConfigSpec,
+		"Nova":      instance.Spec.Nova.ServiceConfigSpec,
+		"Cinder":    instance.Spec.Cinder.ServiceConfigSpec,
+	}
+}
+
+// validateServiceConfigs checks that the files of the default config
+// overwrites are valid ConfigMap keys and that the custom service configs and
+// their INI files parse before they get rendered
+func validateServiceConfigs(instance *controlplanev1beta1.ControlPlane) error {
+	configs := getServiceConfigs(instance)
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		config := configs[name]
+		if err := util.ValidateINI(config.CustomServiceConfig); err != nil {
+			return fmt.Errorf("customServiceConfig of %s is not valid INI: %v", strings.ToLower(name), err)
+		}
+		for file, content := range config.DefaultConfigOverwrite {
+			// the files become keys of the config ConfigMap of the service
+			if errs := validation.IsConfigMapKey(file); len(errs) > 0 {
+				return fmt.Errorf("defaultConfigOverwrite %q of %s is not a valid file name: %s", file, strings.ToLower(name), strings.Join(errs, ", "))
+			}
+			if !strings.HasSuffix(file, ".conf") && !strings.HasSuffix(file, ".ini") {
+				continue
+			}
+			if err := util.ValidateINI(content); err != nil {
+				return fmt.Errorf("defaultConfigOverwrite %s of %s is not valid INI: %v", file, strings.ToLower(name), err)
+			}
+		}
+	}
+	return nil
+}
+
+// getConditionalObjects returns the kind/name of the objects which only get
+// rendered once the cluster reached a certain state, e.g. the service of a
+// child operator exists
+func getConditionalObjects(instance *controlplanev1beta1.ControlPlane) map[string]bool {
+	conditional := map[string]bool{}
+	// load balancers wait for the pod selector of the child service
+	if instance.Spec.Exposure.Type == controlplanev1beta1.ExposureLoadBalancer {
+		for _, svc := range getExposedServices(instance) {
+			conditional["Service/"+getExposureName(svc)] = true
+		}
+	}
+	// budgets wait for the pod selector of the child service as well and
+	// aren't rendered for quiesced services
+	for _, svc := range getDisruptionBudgetServices(&instance.Spec) {
+		conditional["PodDisruptionBudget/"+getDisruptionBudgetName(svc)] = true
+	}
+	return conditional
+}
+
+// applyOverrides patches the rendered objects matching kind and name of the
+// overrides. Overrides of objects which aren't rendered yet, but will be once
+// the cluster reached the state they need, are skipped, overrides without
+// any possible target are rejected.
+func applyOverrides(instance *controlplanev1beta1.ControlPlane, objs []*uns.Unstructured, scheme *runtime.Scheme) error {
+	patchTypes := map[string]types.PatchType{
+		controlplanev1beta1.OverrideStrategic: types.StrategicMergePatchType,
+		controlplanev1beta1.OverrideMerge:     types.MergePatchType,
+		controlplanev1beta1.OverrideJSON:      types.JSONPatchType,
+	}
+	conditional := getConditionalObjects(instance)
+
+	for _, override := range instance.Spec.Overrides {
+		patchType, ok := patchTypes[override.Type]
+		if !ok {
+			return fmt.Errorf("override of %s %s has unknown type %s", override.Kind, override.Name, override.Type)
+		}
+
+		found := false
+		for _, obj := range objs {
+			if obj.GetKind() != override.Kind || obj.GetName() != override.Name {
+				continue
+			}
+			found = true
+			if err := bindatautil.PatchObject(obj, patchType, override.Patch, scheme); err != nil {
+				return fmt.Errorf("failed to apply override of %s %s: %v", override.Kind, override.Name, err)
+			}
+		}
+		if found {
+			continue
+		}
+		if conditional[override.Kind+"/"+override.Name] {
+			ctrl.Log.Info("Skipping override of object which is not rendered yet", "Kind", override.Kind, "Name", override.Name)
+			continue
+		}
+		return fmt.Errorf("override target %s %s is not rendered by the control plane", override.Kind, override.Name)
+	}
+	return nil
 }
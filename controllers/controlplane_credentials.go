@@ -0,0 +1,502 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	"github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/util"
+)
+
+const (
+	// annotation on the ControlPlane, a new value starts a password rotation
+	rotatePasswordsAnnotation = "controlplane.openstack.org/rotate-passwords"
+	// annotation on the service secrets with the time their passwords were rotated
+	rotatedAtAnnotation = "controlplane.openstack.org/rotated-at"
+	// length of the generated passwords
+	rotationPasswordLength = 32
+	// interval the progress of a rotation gets checked in
+	rotationRequeue = time.Second * 10
+)
+
+// serviceCredentialKey - the secret keys of the passwords of a service and
+// the users they belong to
+type serviceCredentialKey struct {
+	service string
+	// secret key of the database password
+	database string
+	// database users sharing the database password
+	databaseUsers []string
+	// secret key of the Keystone service user password, empty if the service
+	// has no service user
+	keystoneAuth string
+	keystoneUser string
+}
+
+// serviceCredentialKeys - the services whose passwords get rotated
+var serviceCredentialKeys = []serviceCredentialKey{
+	{
+		service:       "keystone",
+		database:      "DatabasePassword",
+		databaseUsers: []string{"keystone"},
+	},
+	{
+		service:       "glance",
+		database:      "DatabasePassword",
+		databaseUsers: []string{"glance"},
+		keystoneAuth:  "GlanceKeystoneAuthPassword",
+		keystoneUser:  "glance",
+	},
+	{
+		service:       "placement",
+		database:      "DatabasePassword",
+		databaseUsers: []string{"placement"},
+		keystoneAuth:  "PlacementKeystoneAuthPassword",
+		keystoneUser:  "placement",
+	},
+	{
+		service:       "neutron",
+		database:      "DatabasePassword",
+		databaseUsers: []string{"neutron"},
+		keystoneAuth:  "NeutronKeystoneAuthPassword",
+		keystoneUser:  "neutron",
+	},
+	{
+		service:       "nova",
+		database:      "DatabasePassword",
+		databaseUsers: []string{"nova", "nova_api", "nova_cell0"},
+		keystoneAuth:  "NovaKeystoneAuthPassword",
+		keystoneUser:  "nova",
+	},
+	{
+		service:       "cinder",
+		database:      "DatabasePassword",
+		databaseUsers: []string{"cinder"},
+		keystoneAuth:  "CinderKeystoneAuthPassword",
+		keystoneUser:  "cinder",
+	},
+}
+
+// getRotationOrder returns the services whose passwords get rotated, in
+// rollout order. The passwords in referenced secrets are managed outside of
+// the operator and don't get rotated.
+func getRotationOrder(spec *controlplanev1beta1.ControlPlaneSpec) []serviceCredentialKey {
+	refs := getSecretRefs(spec)
+	order := []serviceCredentialKey{}
+	for _, dir := range serviceManifestDirs {
+		for _, keys := range serviceCredentialKeys {
+			if keys.service == dir && refs[dir] == "" {
+				order = append(order, keys)
+			}
+		}
+	}
+	return order
+}
+
+// isRotationDue checks if a password rotation has to be started, either
+// because the rotate-passwords annotation changed or the rotation period
+// passed since the last rotation. A failed rotation only gets retried with
+// a new annotation value.
+func isRotationDue(instance *controlplanev1beta1.ControlPlane, now time.Time) bool {
+	status := &instance.Status.PasswordRotation
+	if trigger := instance.GetAnnotations()[rotatePasswordsAnnotation]; trigger != "" && trigger != status.Trigger {
+		return true
+	}
+	if instance.Spec.RotationPeriod == nil || status.Phase == controlplanev1beta1.PasswordRotationFailed {
+		return false
+	}
+	last := instance.CreationTimestamp.Time
+	if status.LastRotationTime != nil {
+		last = status.LastRotationTime.Time
+	}
+	return now.Sub(last) >= instance.Spec.RotationPeriod.Duration
+}
+
+// reconcilePasswordRotation starts and advances a password rotation. The
+// services get rotated one after the other: a job changes the passwords of
+// the database and Keystone users, then the new passwords get written into
+// the service secret and the rotation waits for the service to become ready
+// with them. MariaDB users have a single password, so the service can't
+// connect with its old password between the job and the rollout. Returns
+// when to check the progress again, 0 if no rotation is in progress.
+func reconcilePasswordRotation(ctx context.Context, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane, objs []*uns.Unstructured) (time.Duration, error) {
+	status := &instance.Status.PasswordRotation
+	order := getRotationOrder(&instance.Spec)
+
+	if status.Phase != controlplanev1beta1.PasswordRotationRotating {
+		// only rotate a fully deployed control plane, the restore needs the
+		// passwords of the dump
+		if !instance.Status.Ready || instance.GetAnnotations()[quiesceAnnotation] != "" ||
+			len(order) == 0 || !isRotationDue(instance, time.Now()) {
+			return 0, nil
+		}
+		// jobs of a failed rotation would fail the new one right away, the
+		// pending passwords are kept, some users may have them already
+		for _, keys := range order {
+			job := &batchv1.Job{}
+			job.Name = getRotationJobName(instance, keys.service)
+			job.Namespace = instance.Namespace
+			if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8s_errors.IsNotFound(err) {
+				return 0, err
+			}
+		}
+		now := metav1.Now()
+		log.Info("Starting password rotation", "Service", order[0].service)
+		status.Phase = controlplanev1beta1.PasswordRotationRotating
+		status.Service = order[0].service
+		status.Step = controlplanev1beta1.PasswordRotationUpdatingCredentials
+		status.Trigger = instance.GetAnnotations()[rotatePasswordsAnnotation]
+		status.StartTime = &now
+		status.Message = ""
+	}
+
+	current := -1
+	for i, keys := range order {
+		if keys.service == status.Service {
+			current = i
+		}
+	}
+	if current < 0 {
+		status.Phase = controlplanev1beta1.PasswordRotationFailed
+		status.Message = fmt.Sprintf("unknown service %q", status.Service)
+		return 0, nil
+	}
+	keys := order[current]
+
+	switch status.Step {
+	case controlplanev1beta1.PasswordRotationUpdatingCredentials:
+		pending, err := ensurePendingPasswords(ctx, c, instance, keys)
+		if err != nil {
+			return 0, err
+		}
+
+		job := &batchv1.Job{}
+		err = c.Get(ctx, types.NamespacedName{Name: getRotationJobName(instance, keys.service), Namespace: instance.Namespace}, job)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return 0, err
+		}
+		if k8s_errors.IsNotFound(err) {
+			job = getRotationJob(instance, keys)
+			job.SetOwnerReferences([]metav1.OwnerReference{*metav1.NewControllerRef(instance, instance.GroupVersionKind())})
+			log.Info("Creating password rotation job", "Job", job.Name)
+			if err := c.Create(ctx, job); err != nil && !k8s_errors.IsAlreadyExists(err) {
+				return 0, err
+			}
+			return rotationRequeue, nil
+		}
+
+		// a job of an earlier rotation which is still being deleted
+		if status.StartTime != nil && !job.CreationTimestamp.IsZero() && job.CreationTimestamp.Before(status.StartTime) {
+			if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8s_errors.IsNotFound(err) {
+				return 0, err
+			}
+			return rotationRequeue, nil
+		}
+
+		switch phase, _ := getJobPhase(job); phase {
+		case controlplanev1beta1.CommandPhaseFailed:
+			log.Info("Password rotation job failed", "Job", job.Name)
+			status.Phase = controlplanev1beta1.PasswordRotationFailed
+			status.Message = fmt.Sprintf("job %s failed, the passwords of %s may have been partially changed", job.Name, keys.service)
+			return 0, nil
+		case controlplanev1beta1.CommandPhaseSucceeded:
+		default:
+			return rotationRequeue, nil
+		}
+
+		// the users got the new passwords, hand them to the service
+		if err := updateServiceSecret(ctx, c, instance, keys, pending); err != nil {
+			return 0, err
+		}
+		if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !k8s_errors.IsNotFound(err) {
+			return 0, err
+		}
+		log.Info("Rolling out the new passwords", "Service", keys.service)
+		status.Step = controlplanev1beta1.PasswordRotationRollingOut
+		return rotationRequeue, nil
+
+	case controlplanev1beta1.PasswordRotationRollingOut:
+		// the child resources have to be applied with the hash of the new
+		// passwords and be observed by their operator, before their
+		// readiness tells anything about the new passwords
+		serviceObjs := getPhaseObjects(objs, []string{keys.service})
+		unobserved, err := getUnobservedObjects(ctx, c, serviceObjs)
+		if err != nil {
+			return 0, err
+		}
+		if len(unobserved) > 0 {
+			log.Info("Waiting for the new passwords to be observed", "Service", keys.service, "WaitingFor", unobserved)
+			return rotationRequeue, nil
+		}
+		// objects of unknown readiness passed the rollout already
+		notReady, _, err := getNotReadyObjects(ctx, c, serviceObjs)
+		if err != nil {
+			return 0, err
+		}
+		if len(notReady) > 0 {
+			return rotationRequeue, nil
+		}
+
+		if current+1 < len(order) {
+			status.Service = order[current+1].service
+			status.Step = controlplanev1beta1.PasswordRotationUpdatingCredentials
+			return rotationRequeue, nil
+		}
+
+		pending := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      getPendingPasswordsName(instance),
+				Namespace: instance.Namespace,
+			},
+		}
+		if err := c.Delete(ctx, pending); err != nil && !k8s_errors.IsNotFound(err) {
+			return 0, err
+		}
+		now := metav1.Now()
+		log.Info("Password rotation completed")
+		status.Phase = controlplanev1beta1.PasswordRotationCompleted
+		status.Service = ""
+		status.Step = ""
+		status.LastRotationTime = &now
+	}
+	return 0, nil
+}
+
+// getUnobservedObjects returns the rendered objects with a secret hash whose
+// live object has a different hash, or whose operator didn't observe the
+// current generation yet. The hash isn't checked if the CRD of the child
+// resource pruned it, nor the generation if the status doesn't report it.
+func getUnobservedObjects(ctx context.Context, c client.Client, objs []*uns.Unstructured) ([]string, error) {
+	unobserved := []string{}
+	for _, obj := range objs {
+		hash, ok, _ := uns.NestedString(obj.Object, "spec", "secretHash")
+		if !ok {
+			continue
+		}
+		live := &uns.Unstructured{}
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		err := c.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, live)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return nil, err
+		}
+		if err != nil {
+			unobserved = append(unobserved, obj.GetKind()+"/"+obj.GetName())
+			continue
+		}
+		liveHash, hasHash, _ := uns.NestedString(live.Object, "spec", "secretHash")
+		observed, hasObserved, _ := uns.NestedInt64(live.Object, "status", "observedGeneration")
+		if (hasHash && liveHash != hash) || (hasObserved && observed < live.GetGeneration()) {
+			unobserved = append(unobserved, obj.GetKind()+"/"+obj.GetName())
+		}
+	}
+	return unobserved, nil
+}
+
+// getPendingPasswordsName returns the name of the secret holding the new
+// passwords until they are written into the service secrets
+func getPendingPasswordsName(instance *controlplanev1beta1.ControlPlane) string {
+	return instance.Name + "-password-rotation"
+}
+
+func getRotationJobName(instance *controlplanev1beta1.ControlPlane, service string) string {
+	return fmt.Sprintf("%s-rotate-%s", instance.Name, service)
+}
+
+// ensurePendingPasswords generates the new passwords of a service, unless
+// they were generated before, and returns the pending passwords secret. The
+// passwords are kept until the rotation completed, so a recreated job sets
+// the same passwords.
+func ensurePendingPasswords(ctx context.Context, c client.Client, instance *controlplanev1beta1.ControlPlane, keys serviceCredentialKey) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: getPendingPasswordsName(instance), Namespace: instance.Namespace}, secret)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return nil, err
+	}
+	create := k8s_errors.IsNotFound(err)
+	if create {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      getPendingPasswordsName(instance),
+				Namespace: instance.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(instance, instance.GroupVersionKind()),
+				},
+			},
+		}
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	changed := false
+	for _, key := range getPendingPasswordKeys(keys) {
+		if _, ok := secret.Data[key]; ok {
+			continue
+		}
+		password, err := util.GeneratePassword(rotationPasswordLength)
+		if err != nil {
+			return nil, err
+		}
+		secret.Data[key] = []byte(password)
+		changed = true
+	}
+
+	switch {
+	case create:
+		err = c.Create(ctx, secret)
+	case changed:
+		err = c.Update(ctx, secret)
+	}
+	return secret, err
+}
+
+// getPendingPasswordKeys returns the keys of the new passwords of a service
+// in the pending passwords secret
+func getPendingPasswordKeys(keys serviceCredentialKey) []string {
+	pending := []string{keys.service + "-database"}
+	if keys.keystoneAuth != "" {
+		pending = append(pending, keys.service+"-keystone-auth")
+	}
+	return pending
+}
+
+// updateServiceSecret writes the new passwords into the service secret. The
+// rendered secrets keep the passwords of the existing secrets, so they
+// don't get reverted by the next reconcile.
+func updateServiceSecret(ctx context.Context, c client.Client, instance *controlplanev1beta1.ControlPlane, keys serviceCredentialKey, pending *corev1.Secret) error {
+	secret := &corev1.Secret{}
+	name := getServiceSecretName(getServiceSecrets(&instance.Spec), keys.service)
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: instance.Namespace}, secret)
+	if err != nil {
+		return err
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data[keys.database] = pending.Data[keys.service+"-database"]
+	if keys.keystoneAuth != "" {
+		secret.Data[keys.keystoneAuth] = pending.Data[keys.service+"-keystone-auth"]
+	}
+
+	annotations := secret.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[rotatedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	secret.SetAnnotations(annotations)
+	return c.Update(ctx, secret)
+}
+
+// getRotationJob returns a job changing the passwords of the database users
+// of a service to the pending ones, followed by the password of its Keystone
+// service user. The generated passwords are alphanumeric and don't need
+// quoting in SQL.
+func getRotationJob(instance *controlplanev1beta1.ControlPlane, keys serviceCredentialKey) *batchv1.Job {
+	backoffLimit := backupBackoffLimit
+	pendingName := getPendingPasswordsName(instance)
+
+	secrets := getServiceSecrets(&instance.Spec)
+	database := getMariaDBContainer("database", secrets.MariaDB, []string{
+		"set -o pipefail",
+		fmt.Sprintf("for user in %s; do", strings.Join(keys.databaseUsers, " ")),
+		fmt.Sprintf("  mysql -h %s -u root -N -B -e \"SELECT host FROM mysql.user WHERE user = '$user'\" | while read -r host; do", mariadbHostname),
+		fmt.Sprintf("    mysql -h %s -u root -e \"ALTER USER '$user'@'$host' IDENTIFIED BY '$NEW_PASSWORD'\"", mariadbHostname),
+		"  done",
+		"done",
+	})
+	database.VolumeMounts = nil
+	database.Env = append(database.Env, getSecretEnv("NEW_PASSWORD", pendingName, keys.service+"-database"))
+
+	containers := []corev1.Container{database}
+	if keys.keystoneAuth != "" {
+		containers = append(containers, corev1.Container{
+			Name:  "keystone",
+			Image: getServiceImages().Keystone,
+			Command: []string{"/bin/bash", "-c", strings.Join([]string{
+				"set -e",
+				fmt.Sprintf("openstack user set --password \"$NEW_PASSWORD\" %s", keys.keystoneUser),
+			}, "\n")},
+			Env: []corev1.EnvVar{
+				{Name: "OS_AUTH_URL", Value: fmt.Sprintf("http://keystone.%s.svc:5000/v3", instance.Namespace)},
+				{Name: "OS_IDENTITY_API_VERSION", Value: "3"},
+				{Name: "OS_USERNAME", Value: "admin"},
+				{Name: "OS_PROJECT_NAME", Value: "admin"},
+				{Name: "OS_USER_DOMAIN_NAME", Value: "Default"},
+				{Name: "OS_PROJECT_DOMAIN_NAME", Value: "Default"},
+				getSecretEnv("OS_PASSWORD", secrets.Keystone, "AdminPassword"),
+				getSecretEnv("NEW_PASSWORD", pendingName, keys.service+"-keystone-auth"),
+			},
+		})
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getRotationJobName(instance, keys.service),
+			Namespace: instance.Namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": "controlplanerotation",
+					},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+				},
+			},
+		},
+	}
+	// the database users get changed first, the job gets retried as a whole
+	podSpec := &job.Spec.Template.Spec
+	if len(containers) > 1 {
+		podSpec.InitContainers = containers[:1]
+		podSpec.Containers = containers[1:]
+	} else {
+		podSpec.Containers = containers
+	}
+	return job
+}
+
+// getSecretEnv returns an env var taken from a secret key
+func getSecretEnv(name string, secret string, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secret,
+				},
+				Key: key,
+			},
+		},
+	}
+}
@@ -0,0 +1,242 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+func TestReconcilePasswordRotation(t *testing.T) {
+	keystoneAPI := schema.GroupVersionKind{Group: "keystone.openstack.org", Version: "v1beta1", Kind: "KeystoneAPI"}
+	s := runtime.NewScheme()
+	_ = scheme.AddToScheme(s)
+	_ = controlplanev1beta1.AddToScheme(s)
+	s.AddKnownTypeWithName(keystoneAPI, &uns.Unstructured{})
+	s.AddKnownTypeWithName(keystoneAPI.GroupVersion().WithKind("KeystoneAPIList"), &uns.UnstructuredList{})
+
+	instance := &controlplanev1beta1.ControlPlane{ObjectMeta: metav1.ObjectMeta{
+		Name:        "cp",
+		Namespace:   "openstack",
+		Annotations: map[string]string{rotatePasswordsAnnotation: "1"},
+	}}
+	setDefaults(instance)
+	instance.Status.Ready = true
+	secretName := getServiceSecrets(&instance.Spec).Keystone
+
+	// the child resource as applied before the rotation
+	live := &uns.Unstructured{}
+	live.SetGroupVersionKind(keystoneAPI)
+	live.SetName("keystone")
+	live.SetNamespace("openstack")
+	live.SetGeneration(1)
+	_ = uns.SetNestedField(live.Object, "old", "spec", "secretHash")
+	_ = uns.SetNestedField(live.Object, int64(1), "status", "observedGeneration")
+	_ = uns.SetNestedField(live.Object, true, "status", "ready")
+
+	c := fake.NewFakeClientWithScheme(s, live, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: "openstack"},
+		Data:       map[string][]byte{"AdminPassword": []byte("admin"), "DatabasePassword": []byte("old")},
+	})
+	ctx := context.TODO()
+	log := logf.NullLogger{}
+
+	rendered := live.DeepCopy()
+	delete(rendered.Object, "status")
+	_ = uns.SetNestedField(rendered.Object, "new", "spec", "secretHash")
+	setServiceLabel([]*uns.Unstructured{rendered}, "keystone")
+	objs := []*uns.Unstructured{rendered}
+
+	expectStep := func(service string, step string) {
+		t.Helper()
+		status := instance.Status.PasswordRotation
+		if status.Phase != controlplanev1beta1.PasswordRotationRotating || status.Service != service || status.Step != step {
+			t.Fatalf("expected %s of %s, got %s %s %s", step, service, status.Phase, status.Service, status.Step)
+		}
+	}
+
+	// the annotation starts the rotation with the first service
+	if _, err := reconcilePasswordRotation(ctx, c, log, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+	expectStep("keystone", controlplanev1beta1.PasswordRotationUpdatingCredentials)
+	job := &batchv1.Job{}
+	jobKey := types.NamespacedName{Name: getRotationJobName(instance, "keystone"), Namespace: "openstack"}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("expected the rotation job to be created: %v", err)
+	}
+
+	// the job is still running
+	if _, err := reconcilePasswordRotation(ctx, c, log, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+	expectStep("keystone", controlplanev1beta1.PasswordRotationUpdatingCredentials)
+
+	// the job succeeded, the new password gets written into the service secret
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reconcilePasswordRotation(ctx, c, log, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+	expectStep("keystone", controlplanev1beta1.PasswordRotationRollingOut)
+	pending := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: getPendingPasswordsName(instance), Namespace: "openstack"}, pending); err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: secretName, Namespace: "openstack"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["DatabasePassword"]) != string(pending.Data["keystone-database"]) {
+		t.Errorf("expected the pending password in the service secret")
+	}
+	if err := c.Get(ctx, jobKey, &batchv1.Job{}); err == nil {
+		t.Errorf("expected the rotation job to be deleted")
+	}
+
+	// the child resource still has the hash of the old passwords
+	if _, err := reconcilePasswordRotation(ctx, c, log, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+	expectStep("keystone", controlplanev1beta1.PasswordRotationRollingOut)
+
+	// the new hash got applied, but the child operator didn't observe it yet
+	if err := c.Get(ctx, client.ObjectKey{Name: "keystone", Namespace: "openstack"}, live); err != nil {
+		t.Fatal(err)
+	}
+	_ = uns.SetNestedField(live.Object, "new", "spec", "secretHash")
+	live.SetGeneration(2)
+	if err := c.Update(ctx, live); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reconcilePasswordRotation(ctx, c, log, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+	expectStep("keystone", controlplanev1beta1.PasswordRotationRollingOut)
+
+	// the child operator observed the new generation and is ready
+	_ = uns.SetNestedField(live.Object, int64(2), "status", "observedGeneration")
+	if err := c.Update(ctx, live); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reconcilePasswordRotation(ctx, c, log, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+	next := getRotationOrder(&instance.Spec)[1].service
+	expectStep(next, controlplanev1beta1.PasswordRotationUpdatingCredentials)
+
+	// a failed job fails the rotation
+	if _, err := reconcilePasswordRotation(ctx, c, log, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+	job = &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Name: getRotationJobName(instance, next), Namespace: "openstack"}, job); err != nil {
+		t.Fatal(err)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	if err := c.Status().Update(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reconcilePasswordRotation(ctx, c, log, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+	if instance.Status.PasswordRotation.Phase != controlplanev1beta1.PasswordRotationFailed {
+		t.Errorf("expected the rotation to fail, got %s", instance.Status.PasswordRotation.Phase)
+	}
+	failedPending := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: getPendingPasswordsName(instance), Namespace: "openstack"}, failedPending); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed rotation is only retried with a new annotation value
+	if isRotationDue(instance, metav1.Now().Time) {
+		t.Errorf("expected no retry of the failed rotation")
+	}
+	instance.Annotations[rotatePasswordsAnnotation] = "2"
+	if !isRotationDue(instance, metav1.Now().Time) {
+		t.Errorf("expected a new annotation value to start a rotation")
+	}
+
+	// the retry starts over without the failed job of the last rotation
+	if _, err := reconcilePasswordRotation(ctx, c, log, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+	expectStep("keystone", controlplanev1beta1.PasswordRotationUpdatingCredentials)
+	if err := c.Get(ctx, types.NamespacedName{Name: getRotationJobName(instance, next), Namespace: "openstack"}, &batchv1.Job{}); err == nil {
+		t.Errorf("expected the failed job to be deleted")
+	}
+	job = &batchv1.Job{}
+	if err := c.Get(ctx, jobKey, job); err != nil {
+		t.Fatalf("expected the rotation job to be created again: %v", err)
+	}
+	if phase, _ := getJobPhase(job); phase == controlplanev1beta1.CommandPhaseFailed || phase == controlplanev1beta1.CommandPhaseSucceeded {
+		t.Errorf("expected a new job, got a %s one", phase)
+	}
+	// the failed service gets the same pending passwords again
+	retried := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Name: getPendingPasswordsName(instance), Namespace: "openstack"}, retried); err != nil {
+		t.Fatal(err)
+	}
+	key := next + "-database"
+	if string(retried.Data[key]) != string(failedPending.Data[key]) {
+		t.Errorf("expected the pending passwords of the failed rotation to be kept")
+	}
+}
+
+func TestRenderedSecretsQuotePasswords(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	setDefaults(instance)
+	password := `p: "a' #b`
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: getServiceSecrets(&instance.Spec).Keystone, Namespace: "openstack"},
+		Data:       map[string][]byte{"AdminPassword": []byte(password), "DatabasePassword": []byte(password)},
+	})
+
+	objs, err := RenderControlPlane(context.TODO(), c, instance, scheme.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, obj := range objs {
+		if obj.GetKind() != "Secret" || obj.GetName() != getServiceSecrets(&instance.Spec).Keystone {
+			continue
+		}
+		for _, key := range []string{"AdminPassword", "DatabasePassword"} {
+			if value, _, _ := uns.NestedString(obj.Object, "stringData", key); value != password {
+				t.Errorf("expected %s %q, got %q", key, password, value)
+			}
+		}
+		return
+	}
+	t.Errorf("keystone secret not rendered")
+}
@@ -0,0 +1,221 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+const defaultHostnameTemplate = "{{ .Service }}-{{ .Namespace }}.{{ .PublicDomain }}"
+
+// exposureKinds - kinds rendered from the exposure manifests
+var exposureKinds = []schema.GroupVersionKind{
+	{Group: "route.openshift.io", Version: "v1", Kind: "Route"},
+	{Group: "networking.k8s.io", Version: "v1beta1", Kind: "Ingress"},
+	{Group: "", Version: "v1", Kind: "Service"},
+}
+
+// exposedService describes a service API which can be made reachable from
+// outside the cluster
+type exposedService struct {
+	// prefix of the render data keys, e.g. Keystone
+	name string
+	// service created by the child operator
+	serviceName string
+	// port of the service API
+	port int
+	// per service endpoint overrides
+	endpoint controlplanev1beta1.EndpointSpec
+}
+
+// getExposedServices returns all service APIs of the control plane
+func getExposedServices(instance *controlplanev1beta1.ControlPlane) []exposedService {
+	return []exposedService{
+		{name: "Keystone", serviceName: "keystone", port: 5000, endpoint: instance.Spec.Keystone.Endpoint},
+		{name: "Glance", serviceName: "glanceapi", port: 9292, endpoint: instance.Spec.Glance.Endpoint},
+		{name: "Placement", serviceName: "placement", port: 8778, endpoint: instance.Spec.Placement.Endpoint},
+		{name: "Neutron", serviceName: "neutronapi", port: 9696, endpoint: instance.Spec.Neutron.Endpoint},
+		{name: "Nova", serviceName: "nova-api", port: 8774, endpoint: instance.Spec.Nova.Endpoint},
+		{name: "Cinder", serviceName: "cinder-api", port: 8776, endpoint: instance.Spec.Cinder.Endpoint},
+	}
+}
+
+// getExposureName returns the name of the route, ingress or service exposing the service API
+func getExposureName(svc exposedService) string {
+	return svc.serviceName + "-public"
+}
+
+// getPublicHostname returns the hostname override of the service or the
+// hostname rendered from the hostname template, empty if there is no public domain
+func getPublicHostname(instance *controlplanev1beta1.ControlPlane, svc exposedService) (string, error) {
+	if svc.endpoint.Hostname != "" {
+		return svc.endpoint.Hostname, nil
+	}
+	if instance.Spec.PublicDomain == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New("hostname").Option("missingkey=error").Parse(instance.Spec.Exposure.HostnameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse hostname template: %v", err)
+	}
+	hostname := bytes.Buffer{}
+	err = tmpl.Execute(&hostname, map[string]string{
+		"Service":      strings.ToLower(svc.name),
+		"Namespace":    instance.Namespace,
+		"PublicDomain": instance.Spec.PublicDomain,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render hostname template: %v", err)
+	}
+	return hostname.String(), nil
+}
+
+// getPublicURL returns the URL the service API is reachable at from outside
+// the cluster, empty if it isn't known upfront
+func getPublicURL(instance *controlplanev1beta1.ControlPlane, svc exposedService, hostname string) string {
+	if instance.Spec.Exposure.Type == controlplanev1beta1.ExposureNone || hostname == "" {
+		return ""
+	}
+	url := getURLScheme(instance) + "://" + hostname
+	if instance.Spec.Exposure.Type == controlplanev1beta1.ExposureLoadBalancer {
+		url = fmt.Sprintf("%s:%d", url, svc.port)
+	}
+	return url
+}
+
+func getURLScheme(instance *controlplanev1beta1.ControlPlane) string {
+	if instance.Spec.Exposure.TLSTermination == controlplanev1beta1.TLSTerminationNone {
+		return "http"
+	}
+	return "https"
+}
+
+// getExposureRenderData returns a copy of the render data for the exposure
+// manifests of the service
+func getExposureRenderData(data *controlPlaneRenderData, svc exposedService) *controlPlaneRenderData {
+	svcData := *data
+	svcData.ExposedService = exposedServiceData{
+		Name:        getExposureName(svc),
+		ServiceName: svc.serviceName,
+		ServicePort: svc.port,
+		Hostname:    data.Endpoints[svc.name].Hostname,
+	}
+	return &svcData
+}
+
+// getServiceSelector returns the pod selector of the service created by the
+// child operator, nil if there is no client or the service doesn't exist yet
+func getServiceSelector(ctx context.Context, c client.Client, namespace string, serviceName string) (map[string]string, error) {
+	if c == nil {
+		return nil, nil
+	}
+	service := &corev1.Service{}
+	err := c.Get(ctx, types.NamespacedName{Name: serviceName, Namespace: namespace}, service)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return service.Spec.Selector, nil
+}
+
+// deleteUnrenderedExposures deletes the routes, ingresses and load balancer
+// services of the ControlPlane which are not rendered anymore, because the
+// exposure type changed or a service lost its public hostname
+func deleteUnrenderedExposures(ctx context.Context, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane, objs []*uns.Unstructured) error {
+	names := map[string]bool{}
+	for _, svc := range getExposedServices(instance) {
+		names[getExposureName(svc)] = true
+	}
+	return deleteUnrenderedObjects(ctx, c, log, instance, objs, exposureKinds, names)
+}
+
+// getExternalURLs returns the external URLs of the exposed service APIs as
+// far as they are known yet, nil if there are none
+func getExternalURLs(ctx context.Context, c client.Client, instance *controlplanev1beta1.ControlPlane) (map[string]string, error) {
+	if instance.Spec.Exposure.Type == controlplanev1beta1.ExposureNone {
+		return nil, nil
+	}
+
+	var urls map[string]string
+
+	for _, svc := range getExposedServices(instance) {
+		key := types.NamespacedName{Name: getExposureName(svc), Namespace: instance.Namespace}
+		hostname, err := getPublicHostname(instance, svc)
+		if err != nil {
+			return nil, err
+		}
+
+		switch instance.Spec.Exposure.Type {
+		case controlplanev1beta1.ExposureRoute:
+			// the router assigns a host if none was requested
+			route := &uns.Unstructured{}
+			route.SetAPIVersion("route.openshift.io/v1")
+			route.SetKind("Route")
+			if err := c.Get(ctx, key, route); err != nil {
+				if k8s_errors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			hostname, _, _ = uns.NestedString(route.Object, "spec", "host")
+		case controlplanev1beta1.ExposureLoadBalancer:
+			if hostname == "" {
+				service := &corev1.Service{}
+				if err := c.Get(ctx, key, service); err != nil {
+					if k8s_errors.IsNotFound(err) {
+						continue
+					}
+					return nil, err
+				}
+				for _, ingress := range service.Status.LoadBalancer.Ingress {
+					hostname = ingress.Hostname
+					if ingress.IP != "" {
+						hostname = ingress.IP
+					}
+				}
+			}
+			if hostname != "" {
+				hostname = fmt.Sprintf("%s:%d", hostname, svc.port)
+			}
+		}
+
+		if hostname != "" {
+			if urls == nil {
+				urls = map[string]string{}
+			}
+			urls[strings.ToLower(svc.name)] = getURLScheme(instance) + "://" + hostname
+		}
+	}
+	return urls, nil
+}
@@ -0,0 +1,69 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+// TestExposureDefaultsToNone checks that nothing gets exposed by default, the
+// Route API only exists on OpenShift
+func TestExposureDefaultsToNone(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	instance.Spec.PublicDomain = "example.com"
+	setDefaults(instance)
+
+	if instance.Spec.Exposure.Type != controlplanev1beta1.ExposureNone {
+		t.Errorf("exposure type %q, expected %q", instance.Spec.Exposure.Type, controlplanev1beta1.ExposureNone)
+	}
+	for _, svc := range getExposedServices(instance) {
+		hostname, err := getPublicHostname(instance, svc)
+		if err != nil {
+			t.Fatalf("getPublicHostname: %v", err)
+		}
+		if url := getPublicURL(instance, svc, hostname); url != "" {
+			t.Errorf("%s: public URL %q, expected none", svc.name, url)
+		}
+	}
+}
+
+func TestGetPublicURL(t *testing.T) {
+	svc := exposedService{name: "Keystone", serviceName: "keystone", port: 5000}
+	tests := []struct {
+		exposure string
+		tls      string
+		hostname string
+		url      string
+	}{
+		{exposure: controlplanev1beta1.ExposureRoute, hostname: "keystone.example.com", url: "https://keystone.example.com"},
+		{exposure: controlplanev1beta1.ExposureIngress, tls: controlplanev1beta1.TLSTerminationNone, hostname: "keystone.example.com", url: "http://keystone.example.com"},
+		{exposure: controlplanev1beta1.ExposureLoadBalancer, hostname: "keystone.example.com", url: "https://keystone.example.com:5000"},
+		{exposure: controlplanev1beta1.ExposureRoute, hostname: "", url: ""},
+		{exposure: controlplanev1beta1.ExposureNone, hostname: "keystone.example.com", url: ""},
+	}
+	for _, tt := range tests {
+		instance := &controlplanev1beta1.ControlPlane{}
+		instance.Spec.Exposure.Type = tt.exposure
+		instance.Spec.Exposure.TLSTermination = tt.tls
+		if url := getPublicURL(instance, svc, tt.hostname); url != tt.url {
+			t.Errorf("%s %q: public URL %q, expected %q", tt.exposure, tt.hostname, url, tt.url)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+// disruptionBudgetKinds - versions the PodDisruptionBudgets can be rendered
+// with, the newest first
+var disruptionBudgetKinds = []schema.GroupVersionKind{
+	{Group: "policy", Version: "v1", Kind: "PodDisruptionBudget"},
+	{Group: "policy", Version: "v1beta1", Kind: "PodDisruptionBudget"},
+}
+
+// disruptionBudgetService describes a service API whose pods get a
+// PodDisruptionBudget when it runs more than one replica
+type disruptionBudgetService struct {
+	// service the budget gets applied with, e.g. keystone
+	name string
+	// service of the API pods created by the child operator
+	serviceName string
+	// number of API replicas
+	replicas int
+}
+
+// getDisruptionBudgetServices returns the service APIs which can get a
+// PodDisruptionBudget
+func getDisruptionBudgetServices(spec *controlplanev1beta1.ControlPlaneSpec) []disruptionBudgetService {
+	return []disruptionBudgetService{
+		{name: "keystone", serviceName: "keystone", replicas: spec.Keystone.Replicas},
+		{name: "glance", serviceName: "glanceapi", replicas: spec.Glance.Replicas},
+		{name: "placement", serviceName: "placement", replicas: spec.Placement.Replicas},
+		{name: "neutron", serviceName: "neutronapi", replicas: spec.Neutron.Replicas},
+		{name: "nova", serviceName: "nova-api", replicas: spec.Nova.NovaAPIReplicas},
+		{name: "cinder", serviceName: "cinder-api", replicas: spec.Cinder.CinderAPIReplicas},
+	}
+}
+
+func getDisruptionBudgetName(svc disruptionBudgetService) string {
+	return svc.serviceName + "-pdb"
+}
+
+// getDisruptionBudgetAPIVersion returns the newest PodDisruptionBudget API
+// version the cluster serves. The client of this Kubernetes version has no
+// policy/v1 types, so the version gets looked up through the RESTMapper of
+// the client with an unstructured list. Without a client policy/v1beta1 is
+// assumed.
+func getDisruptionBudgetAPIVersion(ctx context.Context, c client.Client) (string, error) {
+	if c == nil {
+		return disruptionBudgetKinds[len(disruptionBudgetKinds)-1].GroupVersion().String(), nil
+	}
+	for _, gvk := range disruptionBudgetKinds[:len(disruptionBudgetKinds)-1] {
+		list := &uns.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := c.List(ctx, list, client.Limit(1))
+		if err == nil {
+			return gvk.GroupVersion().String(), nil
+		}
+		if !meta.IsNoMatchError(err) && !runtime.IsNotRegisteredError(err) {
+			return "", err
+		}
+	}
+	return disruptionBudgetKinds[len(disruptionBudgetKinds)-1].GroupVersion().String(), nil
+}
+
+// getDisruptionBudgetRenderData returns the render data of the
+// PodDisruptionBudget of a service
+func getDisruptionBudgetRenderData(data *controlPlaneRenderData, svc disruptionBudgetService, apiVersion string, selector map[string]string) *controlPlaneRenderData {
+	svcData := *data
+	svcData.DisruptionBudget = disruptionBudgetData{
+		APIVersion: apiVersion,
+		Name:       getDisruptionBudgetName(svc),
+		Selector:   selector,
+	}
+	return &svcData
+}
+
+// deleteUnrenderedDisruptionBudgets deletes the PodDisruptionBudgets of the
+// ControlPlane which are not rendered anymore, because their service got
+// scaled down to a single replica or its pod selector isn't known
+func deleteUnrenderedDisruptionBudgets(ctx context.Context, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane, objs []*uns.Unstructured) error {
+	return deleteUnrenderedObjects(ctx, c, log, instance, objs, disruptionBudgetKinds, nil)
+}
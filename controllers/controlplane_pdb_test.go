@@ -0,0 +1,197 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+func TestRenderDisruptionBudgets(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	setDefaults(instance)
+	instance.Spec.Keystone.Replicas = 3
+	instance.Spec.Glance.Replicas = 3
+
+	// only the service of keystone exists yet
+	selector := map[string]string{"app": "keystone-api", "service": "keystone"}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "keystone", Namespace: "openstack"},
+		Spec:       corev1.ServiceSpec{Selector: selector},
+	})
+
+	objs, err := RenderControlPlane(context.TODO(), c, instance, scheme.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budgets := []*uns.Unstructured{}
+	for _, obj := range objs {
+		if obj.GetKind() == "PodDisruptionBudget" {
+			budgets = append(budgets, obj)
+		}
+	}
+	if len(budgets) != 1 || budgets[0].GetName() != "keystone-pdb" {
+		t.Fatalf("expected only the keystone budget, got %v", budgets)
+	}
+	// the client has no policy/v1 types
+	if budgets[0].GetAPIVersion() != "policy/v1beta1" {
+		t.Errorf("expected policy/v1beta1, got %s", budgets[0].GetAPIVersion())
+	}
+	matchLabels, _, _ := uns.NestedStringMap(budgets[0].Object, "spec", "selector", "matchLabels")
+	if !reflect.DeepEqual(matchLabels, selector) {
+		t.Errorf("expected the selector of the keystone service %v, got %v", selector, matchLabels)
+	}
+}
+
+func TestGetDisruptionBudgetAPIVersion(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = scheme.AddToScheme(s)
+	v1 := disruptionBudgetKinds[0]
+	s.AddKnownTypeWithName(v1, &uns.Unstructured{})
+	s.AddKnownTypeWithName(v1.GroupVersion().WithKind(v1.Kind+"List"), &uns.UnstructuredList{})
+
+	for name, test := range map[string]struct {
+		scheme     *runtime.Scheme
+		apiVersion string
+	}{
+		"policy/v1 served":     {scheme: s, apiVersion: "policy/v1"},
+		"policy/v1 not served": {scheme: scheme.Scheme, apiVersion: "policy/v1beta1"},
+	} {
+		apiVersion, err := getDisruptionBudgetAPIVersion(context.TODO(), fake.NewFakeClientWithScheme(test.scheme))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if apiVersion != test.apiVersion {
+			t.Errorf("%s: expected %s, got %s", name, test.apiVersion, apiVersion)
+		}
+	}
+}
+
+func TestDeleteUnrenderedDisruptionBudgets(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = scheme.AddToScheme(s)
+	v1 := disruptionBudgetKinds[0]
+	s.AddKnownTypeWithName(v1, &uns.Unstructured{})
+	s.AddKnownTypeWithName(v1.GroupVersion().WithKind(v1.Kind+"List"), &uns.UnstructuredList{})
+
+	instance := &controlplanev1beta1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cp", Namespace: "openstack", UID: "uid"}}
+	budget := func(name string, uid string) *policyv1beta1.PodDisruptionBudget {
+		return &policyv1beta1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "openstack",
+			Labels:    map[string]string{ownerUIDLabelSelector: uid},
+		}}
+	}
+	v1Budget := &uns.Unstructured{}
+	v1Budget.SetGroupVersionKind(v1)
+	v1Budget.SetName("placement-pdb")
+	v1Budget.SetNamespace("openstack")
+	v1Budget.SetLabels(map[string]string{ownerUIDLabelSelector: "uid"})
+	c := fake.NewFakeClientWithScheme(s,
+		budget("keystone-pdb", "uid"), budget("glanceapi-pdb", "uid"), budget("other-pdb", "other"), v1Budget)
+
+	rendered := &uns.Unstructured{}
+	rendered.SetGroupVersionKind(v1)
+	rendered.SetName("keystone-pdb")
+	rendered.SetNamespace("openstack")
+	if err := deleteUnrenderedDisruptionBudgets(context.TODO(), c, logf.NullLogger{}, instance, []*uns.Unstructured{rendered}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exists := range map[string]bool{
+		"keystone-pdb":  true,
+		"glanceapi-pdb": false,
+		"other-pdb":     true,
+	} {
+		err := c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "openstack"}, &policyv1beta1.PodDisruptionBudget{})
+		if exists && err != nil {
+			t.Errorf("expected %s to be kept: %v", name, err)
+		}
+		if !exists && err == nil {
+			t.Errorf("expected %s to be deleted", name)
+		}
+	}
+	live := &uns.Unstructured{}
+	live.SetGroupVersionKind(v1)
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: "placement-pdb", Namespace: "openstack"}, live); err == nil {
+		t.Errorf("expected the policy/v1 budget placement-pdb to be deleted")
+	}
+}
+
+func TestDisruptionBudgetOverrideBeforeServiceExists(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	setDefaults(instance)
+	instance.Spec.Keystone.Replicas = 3
+	instance.Spec.Overrides = []controlplanev1beta1.OverrideSpec{{
+		Kind:  "PodDisruptionBudget",
+		Name:  "keystone-pdb",
+		Type:  controlplanev1beta1.OverrideMerge,
+		Patch: `{"spec": {"maxUnavailable": 2}}`,
+	}}
+
+	// the override waits for the budget, which waits for the keystone service
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	if _, err := RenderControlPlane(context.TODO(), c, instance, scheme.Scheme); err != nil {
+		t.Fatalf("expected the override to be skipped before the budget is rendered: %v", err)
+	}
+	// cmd/render renders without a client
+	if _, err := RenderControlPlane(context.TODO(), nil, instance, scheme.Scheme); err != nil {
+		t.Fatalf("expected the override to be skipped without a client: %v", err)
+	}
+
+	if err := c.Create(context.TODO(), &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "keystone", Namespace: "openstack"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "keystone-api"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	objs, err := RenderControlPlane(context.TODO(), c, instance, scheme.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched := false
+	for _, obj := range objs {
+		if obj.GetKind() == "PodDisruptionBudget" && obj.GetName() == "keystone-pdb" {
+			maxUnavailable, _, _ := uns.NestedInt64(obj.Object, "spec", "maxUnavailable")
+			patched = maxUnavailable == 2
+		}
+	}
+	if !patched {
+		t.Errorf("expected the override to patch the rendered budget")
+	}
+
+	// a target which is never rendered is still rejected
+	instance.Spec.Overrides[0].Name = "unknown-pdb"
+	if _, err := RenderControlPlane(context.TODO(), c, instance, scheme.Scheme); err == nil {
+		t.Errorf("expected an override without any possible target to be rejected")
+	}
+}
@@ -0,0 +1,487 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	bindatautil "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/bindata_util"
+	"github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/util"
+)
+
+// controlPlaneRenderData - data the bindata templates get rendered with
+type controlPlaneRenderData struct {
+	// namespace the objects get created in
+	Namespace string
+	// defaulted spec of the ControlPlane
+	Spec controlplanev1beta1.ControlPlaneSpec
+	// container images of the services
+	Images serviceImages
+	// names of the secrets of the services
+	Secrets serviceSecrets
+	// passwords of the services, as in the existing secrets
+	Passwords servicePasswords
+	// hashes of the passwords the child resources of the services read,
+	// a rotated password changes the spec of the child resources
+	SecretHashes serviceSecretHashes
+	// pod placement of the services with the default placement applied
+	Placements servicePlacements
+	// compute resources of the services and their components
+	Resources serviceResources
+	// public endpoints of the service APIs, keyed by service, e.g. Keystone
+	Endpoints map[string]publicEndpoint
+	// service the exposure manifests get rendered for, empty for all other manifests
+	ExposedService exposedServiceData
+	// service the PodDisruptionBudget gets rendered for, empty for all other manifests
+	DisruptionBudget disruptionBudgetData
+}
+
+// serviceImages - container images of the services
+type serviceImages struct {
+	MariaDB         string
+	Keystone        string
+	Glance          string
+	Placement       string
+	Neutron         string
+	NovaAPI         string
+	NovaScheduler   string
+	NovaConductor   string
+	NovaMetadata    string
+	NovaNoVNCProxy  string
+	CinderAPI       string
+	CinderScheduler string
+	CinderBackup    string
+	CinderVolume    string
+}
+
+// serviceSecrets - names of the secrets holding passwords and transport URLs
+type serviceSecrets struct {
+	MariaDB               string
+	Interconnect          string
+	Keystone              string
+	Glance                string
+	Placement             string
+	Neutron               string
+	Nova                  string
+	NovaTransportURL      string
+	NovaCell1TransportURL string
+	Cinder                string
+}
+
+// servicePlacements - pod placement of the services
+type servicePlacements struct {
+	MariaDB   controlplanev1beta1.PodPlacementSpec
+	Keystone  controlplanev1beta1.PodPlacementSpec
+	Glance    controlplanev1beta1.PodPlacementSpec
+	Placement controlplanev1beta1.PodPlacementSpec
+	Neutron   controlplanev1beta1.PodPlacementSpec
+	Nova      controlplanev1beta1.PodPlacementSpec
+	Cinder    controlplanev1beta1.PodPlacementSpec
+}
+
+// serviceResources - compute resources of the services and their components
+type serviceResources struct {
+	MariaDB         corev1.ResourceRequirements
+	Interconnect    corev1.ResourceRequirements
+	Keystone        corev1.ResourceRequirements
+	Glance          corev1.ResourceRequirements
+	Placement       corev1.ResourceRequirements
+	Neutron         corev1.ResourceRequirements
+	NovaAPI         corev1.ResourceRequirements
+	NovaScheduler   corev1.ResourceRequirements
+	NovaConductor   corev1.ResourceRequirements
+	NovaMetadata    corev1.ResourceRequirements
+	NovaNoVNCProxy  corev1.ResourceRequirements
+	CinderAPI       corev1.ResourceRequirements
+	CinderScheduler corev1.ResourceRequirements
+	CinderBackup    corev1.ResourceRequirements
+	CinderVolume    corev1.ResourceRequirements
+}
+
+// servicePasswords - passwords stored in the secrets of the services
+type servicePasswords struct {
+	MariaDBRoot   string
+	KeystoneAdmin string
+	Keystone      serviceCredentials
+	Glance        serviceCredentials
+	Placement     serviceCredentials
+	Neutron       serviceCredentials
+	Nova          serviceCredentials
+	Cinder        serviceCredentials
+}
+
+// serviceCredentials - passwords of the database user and the Keystone
+// service user of a service
+type serviceCredentials struct {
+	Database     string
+	KeystoneAuth string
+}
+
+// serviceSecretHashes - hashes of the passwords read by the child resources
+// of the services
+type serviceSecretHashes struct {
+	Keystone  string
+	Glance    string
+	Placement string
+	Neutron   string
+	Nova      string
+	Cinder    string
+}
+
+// publicEndpoint - public hostname and URL of a service API, empty if not known upfront
+type publicEndpoint struct {
+	Hostname  string
+	PublicURL string
+}
+
+// exposedServiceData - service API exposed by a route, ingress or load balancer
+type exposedServiceData struct {
+	// name of the route, ingress or service
+	Name string
+	// service created by the child operator
+	ServiceName string
+	ServicePort int
+	Hostname    string
+	// pod selector of the service created by the child operator, only
+	// looked up for load balancers
+	Selector map[string]string
+}
+
+// disruptionBudgetData - PodDisruptionBudget of the API pods of a service
+type disruptionBudgetData struct {
+	// policy/v1 if the cluster serves it, policy/v1beta1 otherwise
+	APIVersion string
+	// name of the PodDisruptionBudget
+	Name string
+	// selector of the API pods, taken from the service of the child operator
+	Selector map[string]string
+}
+
+// getServiceImages returns the container images of the services
+func getServiceImages() serviceImages {
+	return serviceImages{
+		MariaDB:         "docker.io/tripleomaster/centos-binary-mariadb:current-tripleo",
+		Keystone:        "docker.io/tripleomaster/centos-binary-keystone:current-tripleo",
+		Glance:          "docker.io/tripleomaster/centos-binary-glance-api:current-tripleo",
+		Placement:       "docker.io/tripleomaster/centos-binary-placement-api:current-tripleo",
+		Neutron:         "tripleotrain/rhel-binary-neutron-server-ovn:current-tripleo",
+		NovaAPI:         "docker.io/tripleomaster/centos-binary-nova-api:current-tripleo",
+		NovaScheduler:   "docker.io/tripleomaster/centos-binary-nova-scheduler:current-tripleo",
+		NovaConductor:   "docker.io/tripleomaster/centos-binary-nova-conductor:current-tripleo",
+		NovaMetadata:    "docker.io/tripleomaster/centos-binary-nova-api:current-tripleo",
+		NovaNoVNCProxy:  "docker.io/tripleomaster/centos-binary-nova-novncproxy:current-tripleo",
+		CinderAPI:       "docker.io/tripleomaster/centos-binary-cinder-api:current-tripleo",
+		CinderScheduler: "docker.io/tripleomaster/centos-binary-cinder-scheduler:current-tripleo",
+		CinderBackup:    "docker.io/tripleomaster/centos-binary-cinder-backup:current-tripleo",
+		CinderVolume:    "docker.io/tripleomaster/centos-binary-cinder-volume:current-tripleo",
+	}
+}
+
+// getServiceSecrets returns the names of the secrets of the services, the
+// secrets referenced in the spec replace the ones of the operator
+func getServiceSecrets(spec *controlplanev1beta1.ControlPlaneSpec) serviceSecrets {
+	secrets := serviceSecrets{
+		MariaDB:               "mariadb-secret",
+		Interconnect:          "interconnect-secret",
+		Keystone:              "keystone-secret",
+		Glance:                "glance-secret",
+		Placement:             "placement-secret",
+		Neutron:               "neutron-secret",
+		Nova:                  "nova-secret",
+		NovaTransportURL:      "nova-transport-url",
+		NovaCell1TransportURL: "nova-cell1-transport-url",
+		Cinder:                "cinder-secret",
+	}
+	for _, ref := range []struct {
+		name   *string
+		secret string
+	}{
+		{&secrets.MariaDB, spec.MariaDB.SecretRef},
+		{&secrets.Keystone, spec.Keystone.SecretRef},
+		{&secrets.Glance, spec.Glance.SecretRef},
+		{&secrets.Placement, spec.Placement.SecretRef},
+		{&secrets.Neutron, spec.Neutron.SecretRef},
+		{&secrets.Nova, spec.Nova.SecretRef},
+		{&secrets.Cinder, spec.Cinder.SecretRef},
+	} {
+		if ref.secret != "" {
+			*ref.name = ref.secret
+		}
+	}
+	return secrets
+}
+
+// getDefaultPasswords returns the passwords the secrets of the services get
+// created with
+func getDefaultPasswords() servicePasswords {
+	return servicePasswords{
+		MariaDBRoot:   "foobar123",
+		KeystoneAdmin: "foobar123",
+		Keystone:      serviceCredentials{Database: "foobar123"},
+		Glance:        serviceCredentials{Database: "openstack", KeystoneAuth: "openstack"},
+		Placement:     serviceCredentials{Database: "openstack", KeystoneAuth: "openstack"},
+		Neutron:       serviceCredentials{Database: "password", KeystoneAuth: "foobar123"},
+		Nova:          serviceCredentials{Database: "openstack", KeystoneAuth: "openstack"},
+		Cinder:        serviceCredentials{Database: "openstack", KeystoneAuth: "openstack"},
+	}
+}
+
+// getServicePasswords returns the passwords of the existing secrets, so
+// rotated passwords are kept, and the default passwords for the secrets which
+// don't exist yet. Without a client only the defaults are returned.
+func getServicePasswords(ctx context.Context, c client.Client, namespace string, secrets serviceSecrets) (servicePasswords, error) {
+	passwords := getDefaultPasswords()
+	if c == nil {
+		return passwords, nil
+	}
+
+	fields := map[string]map[string]*string{
+		secrets.MariaDB:  {"DbRootPassword": &passwords.MariaDBRoot},
+		secrets.Keystone: {"AdminPassword": &passwords.KeystoneAdmin},
+	}
+	for _, keys := range serviceCredentialKeys {
+		credentials := passwords.credentials(keys.service)
+		secret := getServiceSecretName(secrets, keys.service)
+		if fields[secret] == nil {
+			fields[secret] = map[string]*string{}
+		}
+		fields[secret][keys.database] = &credentials.Database
+		if keys.keystoneAuth != "" {
+			fields[secret][keys.keystoneAuth] = &credentials.KeystoneAuth
+		}
+	}
+
+	for name, keys := range fields {
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, secret)
+		if err != nil {
+			if k8s_errors.IsNotFound(err) {
+				continue
+			}
+			return passwords, err
+		}
+		for key, field := range keys {
+			if value, ok := secret.Data[key]; ok {
+				*field = string(value)
+			}
+		}
+	}
+	return passwords, nil
+}
+
+// getSecretHashes returns the hashes of the passwords of the secrets each
+// child resource references, e.g. Neutron reads the Nova secret as well
+func getSecretHashes(p *servicePasswords) (serviceSecretHashes, error) {
+	hashes := serviceSecretHashes{}
+	for field, credentials := range map[*string][]interface{}{
+		&hashes.Keystone:  {p.KeystoneAdmin, p.Keystone},
+		&hashes.Glance:    {p.Glance},
+		&hashes.Placement: {p.Placement},
+		&hashes.Neutron:   {p.Neutron, p.Nova},
+		&hashes.Nova:      {p.Nova, p.Placement, p.Neutron},
+		&hashes.Cinder:    {p.Cinder, p.Nova},
+	} {
+		hash, err := util.CalculateHash(credentials)
+		if err != nil {
+			return hashes, err
+		}
+		*field = hash
+	}
+	return hashes, nil
+}
+
+// credentials returns the credentials of a service, e.g. glance
+func (p *servicePasswords) credentials(service string) *serviceCredentials {
+	switch service {
+	case "keystone":
+		return &p.Keystone
+	case "glance":
+		return &p.Glance
+	case "placement":
+		return &p.Placement
+	case "neutron":
+		return &p.Neutron
+	case "nova":
+		return &p.Nova
+	case "cinder":
+		return &p.Cinder
+	}
+	return nil
+}
+
+// getServiceSecretName returns the name of the secret holding the
+// credentials of a service, e.g. glance
+func getServiceSecretName(secrets serviceSecrets, service string) string {
+	switch service {
+	case "mariadb":
+		return secrets.MariaDB
+	case "keystone":
+		return secrets.Keystone
+	case "glance":
+		return secrets.Glance
+	case "placement":
+		return secrets.Placement
+	case "neutron":
+		return secrets.Neutron
+	case "nova":
+		return secrets.Nova
+	case "cinder":
+		return secrets.Cinder
+	}
+	return ""
+}
+
+// getServicePlacements returns the pod placement of the services, the fields
+// set on a service replace the ones of the default placement
+func getServicePlacements(spec *controlplanev1beta1.ControlPlaneSpec) servicePlacements {
+	return servicePlacements{
+		MariaDB:   mergePodPlacement(spec.DefaultPlacement, spec.MariaDB.PodPlacement),
+		Keystone:  mergePodPlacement(spec.DefaultPlacement, spec.Keystone.PodPlacement),
+		Glance:    mergePodPlacement(spec.DefaultPlacement, spec.Glance.PodPlacement),
+		Placement: mergePodPlacement(spec.DefaultPlacement, spec.Placement.PodPlacement),
+		Neutron:   mergePodPlacement(spec.DefaultPlacement, spec.Neutron.PodPlacement),
+		Nova:      mergePodPlacement(spec.DefaultPlacement, spec.Nova.PodPlacement),
+		Cinder:    mergePodPlacement(spec.DefaultPlacement, spec.Cinder.PodPlacement),
+	}
+}
+
+// mergePodPlacement returns the default placement with the fields set on the
+// service replaced
+func mergePodPlacement(defaults controlplanev1beta1.PodPlacementSpec, service controlplanev1beta1.PodPlacementSpec) controlplanev1beta1.PodPlacementSpec {
+	placement := *defaults.DeepCopy()
+	if service.NodeSelector != nil {
+		placement.NodeSelector = service.NodeSelector
+	}
+	if service.Tolerations != nil {
+		placement.Tolerations = service.Tolerations
+	}
+	if service.Affinity != nil {
+		placement.Affinity = service.Affinity
+	}
+	if service.TopologySpreadConstraints != nil {
+		placement.TopologySpreadConstraints = service.TopologySpreadConstraints
+	}
+	return *placement.DeepCopy()
+}
+
+// getServiceResources returns the compute resources of the services, the
+// resources of a Nova or Cinder component replace the ones of the service
+func getServiceResources(spec *controlplanev1beta1.ControlPlaneSpec) serviceResources {
+	component := func(service corev1.ResourceRequirements, component *corev1.ResourceRequirements) corev1.ResourceRequirements {
+		if component != nil {
+			return *component.DeepCopy()
+		}
+		return *service.DeepCopy()
+	}
+	return serviceResources{
+		MariaDB:         *spec.MariaDB.Resources.DeepCopy(),
+		Interconnect:    *spec.Interconnect.Resources.DeepCopy(),
+		Keystone:        *spec.Keystone.Resources.DeepCopy(),
+		Glance:          *spec.Glance.Resources.DeepCopy(),
+		Placement:       *spec.Placement.Resources.DeepCopy(),
+		Neutron:         *spec.Neutron.Resources.DeepCopy(),
+		NovaAPI:         component(spec.Nova.Resources, spec.Nova.NovaAPIResources),
+		NovaScheduler:   component(spec.Nova.Resources, spec.Nova.NovaSchedulerResources),
+		NovaConductor:   component(spec.Nova.Resources, spec.Nova.NovaConductorResources),
+		NovaMetadata:    component(spec.Nova.Resources, spec.Nova.NovaMetadataResources),
+		NovaNoVNCProxy:  component(spec.Nova.Resources, spec.Nova.NovaNoVNCProxyResources),
+		CinderAPI:       component(spec.Cinder.Resources, spec.Cinder.CinderAPIResources),
+		CinderScheduler: component(spec.Cinder.Resources, spec.Cinder.CinderSchedulerResources),
+		CinderBackup:    component(spec.Cinder.Resources, spec.Cinder.CinderBackupResources),
+		CinderVolume:    component(spec.Cinder.Resources, spec.Cinder.CinderVolumeResources),
+	}
+}
+
+// makeRenderData returns the render data of the templates with the template
+// functions shared by the services
+func makeRenderData(values *controlPlaneRenderData) bindatautil.RenderData {
+	data := bindatautil.MakeTypedRenderData(values)
+	data.Funcs["placement"] = renderPlacement
+	return data
+}
+
+// renderPlacement returns the pod placement fields of a child resource as
+// YAML lines, empty if no placement is set. The templates indent them, e.g.
+// {{- placement .Placements.Keystone | nindent 2 }}
+func renderPlacement(placement controlplanev1beta1.PodPlacementSpec) (string, error) {
+	lines := []string{}
+	for _, field := range []struct {
+		key   string
+		set   bool
+		value interface{}
+	}{
+		{"nodeSelector", len(placement.NodeSelector) > 0, placement.NodeSelector},
+		{"tolerations", len(placement.Tolerations) > 0, placement.Tolerations},
+		{"affinity", placement.Affinity != nil, placement.Affinity},
+		{"topologySpreadConstraints", len(placement.TopologySpreadConstraints) > 0, placement.TopologySpreadConstraints},
+	} {
+		if !field.set {
+			continue
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, field.key+": "+string(value))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// getRenderData returns the data the templates get rendered with, the
+// instance is expected to be defaulted
+func getRenderData(ctx context.Context, client client.Client, instance *controlplanev1beta1.ControlPlane) (*controlPlaneRenderData, error) {
+	data := &controlPlaneRenderData{
+		Namespace:  instance.Namespace,
+		Spec:       *instance.Spec.DeepCopy(),
+		Images:     getServiceImages(),
+		Secrets:    getServiceSecrets(&instance.Spec),
+		Placements: getServicePlacements(&instance.Spec),
+		Resources:  getServiceResources(&instance.Spec),
+		Endpoints:  map[string]publicEndpoint{},
+	}
+
+	passwords, err := getServicePasswords(ctx, client, instance.Namespace, data.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	data.Passwords = passwords
+	data.SecretHashes, err = getSecretHashes(&passwords)
+	if err != nil {
+		return nil, err
+	}
+
+	// public endpoints of the service APIs
+	for _, svc := range getExposedServices(instance) {
+		hostname, err := getPublicHostname(instance, svc)
+		if err != nil {
+			return nil, err
+		}
+		data.Endpoints[svc.name] = publicEndpoint{
+			Hostname:  hostname,
+			PublicURL: getPublicURL(instance, svc, hostname),
+		}
+	}
+	return data, nil
+}
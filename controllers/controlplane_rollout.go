@@ -0,0 +1,213 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// label on the rendered objects with the service they belong to
+	serviceLabelSelector = "controlplane.openstack.org/service"
+
+	// bounds of the delay between checks of a rollout phase
+	rolloutMinBackoff = time.Second * 5
+	rolloutMaxBackoff = time.Minute * 2
+
+	// time a rollout phase waits for custom resources whose status doesn't
+	// tell if they are ready, before they are assumed to be ready
+	rolloutUnknownReadinessTimeout = time.Minute * 5
+)
+
+// serviceDependencies - bindata directories of the services and the services
+// which have to be ready before they get applied
+var serviceDependencies = map[string][]string{
+	"mariadb":      {},
+	"interconnect": {},
+	"keystone":     {"mariadb"},
+	"glance":       {"mariadb", "interconnect", "keystone"},
+	"placement":    {"mariadb", "keystone"},
+	"neutron":      {"mariadb", "interconnect", "keystone"},
+	"cinder":       {"mariadb", "interconnect", "keystone"},
+	"nova":         {"mariadb", "interconnect", "keystone", "placement", "neutron"},
+}
+
+// rolloutPhases - services which get applied together, each phase only
+// depends on services of the phases before it
+var rolloutPhases = getRolloutPhases(serviceDependencies)
+
+// serviceManifestDirs - bindata directories of the services, in rollout order
+// TODO: how to handle adding additional cinder-volume services using openstack-cluster-operator
+// TODO: how to handle adding additional cells using openstack-cluster-operator
+var serviceManifestDirs = getServiceManifestDirs(rolloutPhases)
+
+// getRolloutPhases orders the services into phases, a service is part of the
+// first phase after all of its dependencies. Panics if the dependencies
+// reference unknown services or contain a cycle.
+func getRolloutPhases(dependencies map[string][]string) [][]string {
+	phases := [][]string{}
+	done := map[string]bool{}
+
+	for len(done) < len(dependencies) {
+		phase := []string{}
+		for service, deps := range dependencies {
+			if done[service] {
+				continue
+			}
+			ready := true
+			for _, dep := range deps {
+				if _, ok := dependencies[dep]; !ok {
+					panic(fmt.Sprintf("service %s depends on unknown service %s", service, dep))
+				}
+				ready = ready && done[dep]
+			}
+			if ready {
+				phase = append(phase, service)
+			}
+		}
+		if len(phase) == 0 {
+			panic("service dependencies contain a cycle")
+		}
+		sort.Strings(phase)
+		for _, service := range phase {
+			done[service] = true
+		}
+		phases = append(phases, phase)
+	}
+	return phases
+}
+
+func getServiceManifestDirs(phases [][]string) []string {
+	dirs := []string{}
+	for _, phase := range phases {
+		dirs = append(dirs, phase...)
+	}
+	return dirs
+}
+
+// getPhaseObjects returns the rendered objects of the services of a phase
+func getPhaseObjects(objs []*uns.Unstructured, phase []string) []*uns.Unstructured {
+	services := map[string]bool{}
+	for _, service := range phase {
+		services[service] = true
+	}
+
+	phaseObjs := []*uns.Unstructured{}
+	for _, obj := range objs {
+		if services[obj.GetLabels()[serviceLabelSelector]] {
+			phaseObjs = append(phaseObjs, obj)
+		}
+	}
+	return phaseObjs
+}
+
+// getNotReadyObjects returns the objects which don't report ready yet and
+// the objects whose readiness is unknown, both as Kind/name
+func getNotReadyObjects(ctx context.Context, c client.Client, objs []*uns.Unstructured) ([]string, []string, error) {
+	notReady := []string{}
+	unknown := []string{}
+	for _, obj := range objs {
+		live := &uns.Unstructured{}
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		err := c.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, live)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return nil, nil, err
+		}
+		if err != nil {
+			notReady = append(notReady, obj.GetKind()+"/"+obj.GetName())
+			continue
+		}
+		ready, known := isObjectReady(live)
+		switch {
+		case !known:
+			unknown = append(unknown, obj.GetKind()+"/"+obj.GetName())
+		case !ready:
+			notReady = append(notReady, obj.GetKind()+"/"+obj.GetName())
+		}
+	}
+	return notReady, unknown, nil
+}
+
+// isObjectReady checks the status of an applied object, known is false if
+// the status doesn't tell. The child operators don't share a status format,
+// so the common readiness indicators are used: a Ready, Available or
+// Deployed condition, a ready flag or ready replica counts. Objects of the
+// core, batch, policy, route and networking API groups don't report
+// readiness and are ready once they exist. Custom resources without a
+// status or without any of the indicators are unknown.
+func isObjectReady(obj *uns.Unstructured) (ready bool, known bool) {
+	switch obj.GroupVersionKind().Group {
+	case "", "batch", "policy", "route.openshift.io", "networking.k8s.io":
+		return true, true
+	}
+
+	status, ok, _ := uns.NestedMap(obj.Object, "status")
+	if !ok {
+		return false, false
+	}
+
+	conditions, _, _ := uns.NestedSlice(status, "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch condition["type"] {
+		case "Ready", "Available", "Deployed":
+			return condition["status"] == "True", true
+		}
+	}
+
+	if ready, ok, _ := uns.NestedBool(status, "ready"); ok {
+		return ready, true
+	}
+
+	for _, field := range []string{"readyReplicas", "readyCount"} {
+		readyReplicas, ok, _ := uns.NestedInt64(status, field)
+		if !ok {
+			continue
+		}
+		replicas, ok, _ := uns.NestedInt64(obj.Object, "spec", "replicas")
+		if !ok {
+			replicas = 1
+		}
+		return readyReplicas >= replicas, true
+	}
+
+	return false, false
+}
+
+// getRolloutBackoff returns the delay until a phase gets checked again, the
+// time already waited for the phase bounded by the min and max backoff, which
+// doubles the delay with every check
+func getRolloutBackoff(waited time.Duration) time.Duration {
+	if waited < rolloutMinBackoff {
+		return rolloutMinBackoff
+	}
+	if waited > rolloutMaxBackoff {
+		return rolloutMaxBackoff
+	}
+	return waited
+}
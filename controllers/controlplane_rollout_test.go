@@ -0,0 +1,76 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestIsObjectReady(t *testing.T) {
+	tests := []struct {
+		name  string
+		obj   map[string]interface{}
+		ready bool
+		known bool
+	}{
+		{
+			name:  "core object",
+			obj:   map[string]interface{}{"apiVersion": "v1", "kind": "Secret"},
+			ready: true, known: true,
+		},
+		{
+			name:  "custom resource without status",
+			obj:   map[string]interface{}{"apiVersion": "keystone.openstack.org/v1", "kind": "KeystoneAPI"},
+			ready: false, known: false,
+		},
+		{
+			name: "custom resource with unrecognized status",
+			obj: map[string]interface{}{"apiVersion": "keystone.openstack.org/v1", "kind": "KeystoneAPI",
+				"status": map[string]interface{}{"hash": "abc"}},
+			ready: false, known: false,
+		},
+		{
+			name: "ready condition",
+			obj: map[string]interface{}{"apiVersion": "keystone.openstack.org/v1", "kind": "KeystoneAPI",
+				"status": map[string]interface{}{"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "False"},
+				}}},
+			ready: false, known: true,
+		},
+		{
+			name: "ready flag",
+			obj: map[string]interface{}{"apiVersion": "keystone.openstack.org/v1", "kind": "KeystoneAPI",
+				"status": map[string]interface{}{"ready": true}},
+			ready: true, known: true,
+		},
+		{
+			name: "ready replicas",
+			obj: map[string]interface{}{"apiVersion": "keystone.openstack.org/v1", "kind": "KeystoneAPI",
+				"spec":   map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{"readyReplicas": int64(2)}},
+			ready: false, known: true,
+		},
+	}
+	for _, tt := range tests {
+		ready, known := isObjectReady(&uns.Unstructured{Object: tt.obj})
+		if ready != tt.ready || known != tt.known {
+			t.Errorf("%s: ready %v known %v, expected ready %v known %v", tt.name, ready, known, tt.ready, tt.known)
+		}
+	}
+}
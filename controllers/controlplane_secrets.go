@@ -0,0 +1,125 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+// requiredSecretKeys - keys the services read from their secret, the
+// referenced secrets need all of them
+var requiredSecretKeys = map[string][]string{
+	"mariadb":   {"DbRootPassword"},
+	"keystone":  {"AdminPassword", "DatabasePassword"},
+	"glance":    {"TransportUrl", "DatabasePassword", "GlanceKeystoneAuthPassword"},
+	"placement": {"DatabasePassword", "PlacementKeystoneAuthPassword"},
+	"neutron":   {"TransportUrl", "DatabasePassword", "NeutronKeystoneAuthPassword"},
+	"nova":      {"DatabasePassword", "NovaKeystoneAuthPassword"},
+	"cinder":    {"TransportUrl", "DatabasePassword", "CinderKeystoneAuthPassword"},
+}
+
+// getSecretRefs returns the secrets referenced in the spec by service
+func getSecretRefs(spec *controlplanev1beta1.ControlPlaneSpec) map[string]string {
+	refs := map[string]string{}
+	for service, ref := range map[string]string{
+		"mariadb":   spec.MariaDB.SecretRef,
+		"keystone":  spec.Keystone.SecretRef,
+		"glance":    spec.Glance.SecretRef,
+		"placement": spec.Placement.SecretRef,
+		"neutron":   spec.Neutron.SecretRef,
+		"nova":      spec.Nova.SecretRef,
+		"cinder":    spec.Cinder.SecretRef,
+	} {
+		if ref != "" {
+			refs[service] = ref
+		}
+	}
+	return refs
+}
+
+// validateSecretRefs checks that the referenced secrets exist and have the
+// keys required by their service. Returns the problems found, sorted by
+// service.
+func validateSecretRefs(ctx context.Context, c client.Client, instance *controlplanev1beta1.ControlPlane) ([]string, error) {
+	refs := getSecretRefs(&instance.Spec)
+	services := []string{}
+	for service := range refs {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	problems := []string{}
+	for _, service := range services {
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Name: refs[service], Namespace: instance.Namespace}, secret)
+		if err != nil {
+			if k8s_errors.IsNotFound(err) {
+				problems = append(problems, fmt.Sprintf("%s: secret %s not found", service, refs[service]))
+				continue
+			}
+			return nil, err
+		}
+
+		missing := []string{}
+		for _, key := range requiredSecretKeys[service] {
+			if _, ok := secret.Data[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: secret %s is missing keys %s", service, refs[service], strings.Join(missing, ", ")))
+		}
+	}
+	return problems, nil
+}
+
+// deleteReplacedSecrets deletes the secrets the operator created for the
+// services which reference an existing secret now. The referenced secret has
+// to hold the current passwords, the replaced ones aren't used anymore.
+func deleteReplacedSecrets(ctx context.Context, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane, objs []*uns.Unstructured) error {
+	refs := getSecretRefs(&instance.Spec)
+	defaults := getServiceSecrets(&controlplanev1beta1.ControlPlaneSpec{})
+	names := map[string]bool{}
+	for service, ref := range refs {
+		name := getServiceSecretName(defaults, service)
+		if name != ref {
+			names[name] = true
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	// a referenced secret can carry the name of a replaced one
+	for _, ref := range refs {
+		delete(names, ref)
+	}
+	kinds := []schema.GroupVersionKind{corev1.SchemeGroupVersion.WithKind("Secret")}
+	return deleteUnrenderedObjects(ctx, c, log, instance, objs, kinds, names)
+}
@@ -0,0 +1,71 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+func TestDeleteReplacedSecrets(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "cp", Namespace: "openstack", UID: "uid"}}
+	instance.Spec.Keystone.SecretRef = "vault-keystone"
+	// referencing a secret named like the one the operator created
+	instance.Spec.Glance.SecretRef = "glance-secret"
+
+	secret := func(name string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "openstack",
+			Labels:    map[string]string{ownerUIDLabelSelector: "uid"},
+		}}
+	}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme,
+		secret("keystone-secret"), secret("glance-secret"), secret("nova-secret"), secret("vault-keystone"))
+
+	objs, err := RenderControlPlane(context.TODO(), c, instance, scheme.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deleteReplacedSecrets(context.TODO(), c, logf.NullLogger{}, instance, objs); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, exists := range map[string]bool{
+		"keystone-secret": false,
+		"glance-secret":   true,
+		"nova-secret":     true,
+		"vault-keystone":  true,
+	} {
+		err := c.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "openstack"}, &corev1.Secret{})
+		if exists && err != nil {
+			t.Errorf("expected %s to be kept: %v", name, err)
+		}
+		if !exists && err == nil {
+			t.Errorf("expected %s to be deleted", name)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+const (
+	// finalizer on the ControlPlane, released once all objects rendered for
+	// it are deleted
+	controlPlaneFinalizer = "controlplane.openstack.org/teardown"
+
+	// annotation on the ControlPlane, if "true" the PersistentVolumeClaims of
+	// the services are kept when the ControlPlane gets deleted
+	orphanDataAnnotation = "controlplane.openstack.org/orphan-data"
+)
+
+// teardown deletes the objects rendered for the ControlPlane service by
+// service, in reverse rollout order. Owner references only get set on
+// objects in the namespace of the ControlPlane, so the objects are found by
+// the owner uid label instead. A phase gets deleted only once the objects of
+// the later phases are gone, so the child operators can clean up while the
+// services they depend on still run. The MariaDB and GlanceAPI objects are
+// kept or get their volume claims snapshotted first, depending on the
+// deletion policy. Returns true once nothing is left. c has to read from the
+// API server, the objects and their volume claims aren't limited to the
+// watched namespace.
+func teardown(ctx context.Context, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane) (bool, error) {
+	kinds, err := getManifestKinds(Manifests)
+	if err != nil {
+		return false, err
+	}
+	// the backup CronJob isn't rendered from a manifest and the API version
+	// of the budgets depends on the cluster
+	kinds = append(kinds, batchv1beta1.SchemeGroupVersion.WithKind("CronJob"))
+	kinds = append(kinds, disruptionBudgetKinds...)
+	orphanData := instance.GetAnnotations()[orphanDataAnnotation] == "true"
+	policy := instance.Spec.DeletionPolicy
+	if policy == "" {
+		policy = controlplanev1beta1.DeletionPolicyDelete
+	}
+
+	credentialSecrets := getCredentialSecrets(&instance.Spec)
+
+	for i := len(rolloutPhases) - 1; i >= 0; i-- {
+		objs, err := getTeardownObjects(ctx, c, instance, kinds, rolloutPhases[i])
+		if err != nil {
+			return false, err
+		}
+		if len(objs) == 0 {
+			continue
+		}
+
+		for _, obj := range objs {
+			if obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			// the kept data can only be accessed with the passwords of the
+			// database and Keystone users
+			if policy != controlplanev1beta1.DeletionPolicyDelete && credentialSecrets[obj.GroupVersionKind().GroupKind()][obj.GetName()] {
+				if err := detachObject(ctx, c, log, instance, obj); err != nil {
+					return false, err
+				}
+				continue
+			}
+			if dataBearingKinds[obj.GroupVersionKind().GroupKind()] {
+				switch policy {
+				case controlplanev1beta1.DeletionPolicyRetain:
+					if err := orphanClaims(ctx, c, log, obj); err != nil {
+						return false, err
+					}
+					if err := detachObject(ctx, c, log, instance, obj); err != nil {
+						return false, err
+					}
+					continue
+				case controlplanev1beta1.DeletionPolicySnapshot:
+					ready, err := snapshotClaims(ctx, c, log, instance, obj)
+					if err != nil {
+						return false, err
+					}
+					if !ready {
+						continue
+					}
+				}
+			}
+			if orphanData {
+				if err := orphanClaims(ctx, c, log, obj); err != nil {
+					return false, err
+				}
+			}
+			log.Info("Deleting object", "Kind", obj.GetKind(), "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+			if err := c.Delete(ctx, obj); err != nil && !k8s_errors.IsNotFound(err) {
+				return false, errors.Wrapf(err, "could not delete %s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+			}
+		}
+		log.Info("Waiting for objects to be deleted", "Phase", i+1, "Services", rolloutPhases[i])
+		return false, nil
+	}
+	return true, nil
+}
+
+// dataBearingKinds - kinds of the child CRs whose volume claims hold the data
+// of the control plane, handled according to the deletion policy
+var dataBearingKinds = map[schema.GroupKind]bool{
+	{Group: "database.openstack.org", Kind: "MariaDB"}: true,
+	{Group: "glance.openstack.org", Kind: "GlanceAPI"}: true,
+}
+
+// getCredentialSecrets returns the secrets rendered for the services which
+// hold the passwords of the database and Keystone users
+func getCredentialSecrets(spec *controlplanev1beta1.ControlPlaneSpec) map[schema.GroupKind]map[string]bool {
+	secrets := getServiceSecrets(spec)
+	names := map[string]bool{}
+	for _, name := range []string{
+		secrets.MariaDB,
+		secrets.Keystone,
+		secrets.Glance,
+		secrets.Placement,
+		secrets.Neutron,
+		secrets.Nova,
+		secrets.Cinder,
+	} {
+		names[name] = true
+	}
+	// referenced secrets aren't rendered and carry no owner label anyway
+	return map[schema.GroupKind]map[string]bool{{Group: "", Kind: "Secret"}: names}
+}
+
+// volumeSnapshotGVK - snapshots taken of the volume claims with the Snapshot deletion policy
+var volumeSnapshotGVK = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1beta1", Kind: "VolumeSnapshot"}
+
+// getTeardownObjects lists the objects of the given kinds carrying the owner
+// uid label of the ControlPlane which belong to the services. Without the
+// permission to list a kind in all namespaces, e.g. when the operator is
+// installed for a single namespace, only the namespace of the ControlPlane
+// is searched.
+func getTeardownObjects(ctx context.Context, c client.Client, instance *controlplanev1beta1.ControlPlane, kinds []schema.GroupVersionKind, services []string) ([]*uns.Unstructured, error) {
+	objs := []*uns.Unstructured{}
+	for _, gvk := range kinds {
+		list := &uns.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := c.List(ctx, list, client.MatchingLabels{ownerUIDLabelSelector: string(instance.UID)})
+		if k8s_errors.IsForbidden(err) {
+			err = c.List(ctx, list, client.InNamespace(instance.Namespace), client.MatchingLabels{ownerUIDLabelSelector: string(instance.UID)})
+		}
+		if err != nil {
+			// the CRDs of optional kinds, e.g. routes, may not be installed
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "could not list %s", gvk.String())
+		}
+		objs = append(objs, getPhaseObjects(listItems(list), services)...)
+	}
+	return objs, nil
+}
+
+// deleteUnrenderedObjects deletes the objects of the given kinds in the
+// namespace of the ControlPlane which carry its owner uid label but are not
+// in objs anymore. If names is set, only objects with one of the names get
+// deleted.
+func deleteUnrenderedObjects(ctx context.Context, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane, objs []*uns.Unstructured, kinds []schema.GroupVersionKind, names map[string]bool) error {
+	rendered := map[schema.GroupKind]map[string]bool{}
+	for _, obj := range objs {
+		gk := obj.GroupVersionKind().GroupKind()
+		if rendered[gk] == nil {
+			rendered[gk] = map[string]bool{}
+		}
+		rendered[gk][obj.GetName()] = true
+	}
+
+	for _, gvk := range kinds {
+		list := &uns.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := c.List(ctx, list, client.InNamespace(instance.Namespace), client.MatchingLabels{ownerUIDLabelSelector: string(instance.UID)})
+		if err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return errors.Wrapf(err, "could not list %s", gvk.String())
+		}
+		for _, obj := range listItems(list) {
+			if rendered[gvk.GroupKind()][obj.GetName()] || (names != nil && !names[obj.GetName()]) || obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			log.Info("Deleting object which is not rendered anymore", "Kind", gvk.Kind, "Name", obj.GetName())
+			if err := c.Delete(ctx, obj); err != nil && !k8s_errors.IsNotFound(err) {
+				return errors.Wrapf(err, "could not delete %s %s/%s", gvk.Kind, obj.GetNamespace(), obj.GetName())
+			}
+		}
+	}
+	return nil
+}
+
+func listItems(list *uns.UnstructuredList) []*uns.Unstructured {
+	items := []*uns.Unstructured{}
+	for i := range list.Items {
+		items = append(items, &list.Items[i])
+	}
+	return items
+}
+
+// orphanClaims removes the owner references to obj from the
+// PersistentVolumeClaims in its namespace, so the garbage collector keeps the
+// data created by the child operators when obj gets deleted
+func orphanClaims(ctx context.Context, c client.Client, log logr.Logger, obj *uns.Unstructured) error {
+	claims, err := getOwnedClaims(ctx, c, obj)
+	if err != nil {
+		return err
+	}
+	for _, claim := range claims {
+		refs := []metav1.OwnerReference{}
+		for _, ref := range claim.OwnerReferences {
+			if ref.UID != obj.GetUID() {
+				refs = append(refs, ref)
+			}
+		}
+		claim.OwnerReferences = refs
+		log.Info("Orphaning persistent volume claim", "Namespace", claim.Namespace, "Name", claim.Name, "Owner", obj.GetKind()+"/"+obj.GetName())
+		if err := c.Update(ctx, claim); err != nil {
+			return errors.Wrapf(err, "could not orphan persistent volume claim %s/%s", claim.Namespace, claim.Name)
+		}
+	}
+	return nil
+}
+
+// getOwnedClaims returns the PersistentVolumeClaims in the namespace of obj
+// which are owned by it
+func getOwnedClaims(ctx context.Context, c client.Client, obj *uns.Unstructured) ([]*corev1.PersistentVolumeClaim, error) {
+	claims := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, claims, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil, errors.Wrapf(err, "could not list persistent volume claims in %s", obj.GetNamespace())
+	}
+	owned := []*corev1.PersistentVolumeClaim{}
+	for i := range claims.Items {
+		for _, ref := range claims.Items[i].OwnerReferences {
+			if ref.UID == obj.GetUID() {
+				owned = append(owned, &claims.Items[i])
+				break
+			}
+		}
+	}
+	return owned, nil
+}
+
+// detachObject removes the owner reference and labels of the ControlPlane
+// from obj, so it is neither garbage collected nor found by the teardown
+func detachObject(ctx context.Context, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane, obj *uns.Unstructured) error {
+	refs := []metav1.OwnerReference{}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID != instance.UID {
+			refs = append(refs, ref)
+		}
+	}
+	obj.SetOwnerReferences(refs)
+
+	labels := obj.GetLabels()
+	delete(labels, ownerUIDLabelSelector)
+	delete(labels, ownerNameSpaceLabelSelector)
+	delete(labels, ownerNameLabelSelector)
+	obj.SetLabels(labels)
+
+	log.Info("Retaining object", "Kind", obj.GetKind(), "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+	if err := c.Update(ctx, obj); err != nil {
+		return errors.Wrapf(err, "could not detach %s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	}
+	return nil
+}
+
+// snapshotClaims creates a VolumeSnapshot of every volume claim owned by obj
+// and returns true once all of them are ready to use
+func snapshotClaims(ctx context.Context, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane, obj *uns.Unstructured) (bool, error) {
+	claims, err := getOwnedClaims(ctx, c, obj)
+	if err != nil {
+		return false, err
+	}
+
+	ready := true
+	for _, claim := range claims {
+		// the snapshot must outlive the ControlPlane, so it gets neither an
+		// owner reference nor the owner uid label
+		snapshot := &uns.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		snapshot.SetNamespace(claim.Namespace)
+		snapshot.SetName(fmt.Sprintf("%s-%.8s", claim.Name, instance.UID))
+
+		err := c.Get(ctx, types.NamespacedName{Name: snapshot.GetName(), Namespace: snapshot.GetNamespace()}, snapshot)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return false, errors.Wrapf(err, "could not get volume snapshot %s/%s", snapshot.GetNamespace(), snapshot.GetName())
+		}
+		if k8s_errors.IsNotFound(err) {
+			snapshot.SetLabels(map[string]string{
+				ownerNameSpaceLabelSelector: instance.Namespace,
+				ownerNameLabelSelector:      instance.Name,
+			})
+			if err := uns.SetNestedField(snapshot.Object, claim.Name, "spec", "source", "persistentVolumeClaimName"); err != nil {
+				return false, err
+			}
+			log.Info("Creating volume snapshot", "Namespace", snapshot.GetNamespace(), "Name", snapshot.GetName(), "Claim", claim.Name)
+			if err := c.Create(ctx, snapshot); err != nil {
+				return false, errors.Wrapf(err, "could not create volume snapshot of %s/%s", claim.Namespace, claim.Name)
+			}
+			ready = false
+			continue
+		}
+
+		if message, ok, _ := uns.NestedString(snapshot.Object, "status", "error", "message"); ok {
+			return false, errors.Errorf("volume snapshot %s/%s failed: %s", snapshot.GetNamespace(), snapshot.GetName(), message)
+		}
+		if readyToUse, _, _ := uns.NestedBool(snapshot.Object, "status", "readyToUse"); !readyToUse {
+			ready = false
+		}
+	}
+	return ready, nil
+}
+
+// getManifestKinds returns the kinds of the objects in the manifests. The
+// apiVersion and kind of the templates are top level keys, so they are read
+// without rendering, which would need a valid spec. Templated ones are
+// skipped.
+func getManifestKinds(fsys fs.FS) ([]schema.GroupVersionKind, error) {
+	seen := map[schema.GroupVersionKind]bool{}
+	kinds := []schema.GroupVersionKind{}
+
+	err := fs.WalkDir(fsys, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !(strings.HasSuffix(path, ".yml") || strings.HasSuffix(path, ".yaml")) {
+			return nil
+		}
+		source, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read manifest %s", path)
+		}
+
+		var apiVersion, kind string
+		scanner := bufio.NewScanner(bytes.NewReader(source))
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "---"):
+				apiVersion, kind = "", ""
+			case strings.HasPrefix(line, "apiVersion:"):
+				apiVersion = strings.TrimSpace(strings.TrimPrefix(line, "apiVersion:"))
+			case strings.HasPrefix(line, "kind:"):
+				kind = strings.TrimSpace(strings.TrimPrefix(line, "kind:"))
+			}
+			if apiVersion == "" || kind == "" {
+				continue
+			}
+			// templated kinds are added by the caller
+			if strings.Contains(apiVersion, "{{") || strings.Contains(kind, "{{") {
+				apiVersion, kind = "", ""
+				continue
+			}
+			gv, err := schema.ParseGroupVersion(apiVersion)
+			if err != nil {
+				return errors.Wrapf(err, "invalid apiVersion in manifest %s", path)
+			}
+			if gvk := gv.WithKind(kind); !seen[gvk] {
+				seen[gvk] = true
+				kinds = append(kinds, gvk)
+			}
+			apiVersion, kind = "", ""
+		}
+		return scanner.Err()
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading manifest kinds")
+	}
+
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].String() < kinds[j].String() })
+	return kinds, nil
+}
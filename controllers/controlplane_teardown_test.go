@@ -0,0 +1,352 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+var (
+	mariaDBGVK     = schema.GroupVersionKind{Group: "database.openstack.org", Version: "v1beta1", Kind: "MariaDB"}
+	glanceAPIGVK   = schema.GroupVersionKind{Group: "glance.openstack.org", Version: "v1beta1", Kind: "GlanceAPI"}
+	keystoneAPIGVK = schema.GroupVersionKind{Group: "keystone.openstack.org", Version: "v1beta1", Kind: "KeystoneAPI"}
+	novaGVK        = schema.GroupVersionKind{Group: "nova.openstack.org", Version: "v1beta1", Kind: "Nova"}
+	secretGVK      = corev1.SchemeGroupVersion.WithKind("Secret")
+)
+
+func TestGetManifestKinds(t *testing.T) {
+	kinds, err := getManifestKinds(Manifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[schema.GroupVersionKind]bool{}
+	for _, gvk := range kinds {
+		if gvk.Group == "" && gvk.Version != "v1" {
+			t.Errorf("unexpected core kind %s", gvk)
+		}
+		found[gvk] = true
+	}
+	for _, gvk := range []schema.GroupVersionKind{mariaDBGVK, glanceAPIGVK, keystoneAPIGVK, novaGVK, secretGVK} {
+		if !found[gvk] {
+			t.Errorf("expected kind %s", gvk)
+		}
+	}
+}
+
+func TestTeardownReverseOrder(t *testing.T) {
+	instance := newTeardownControlPlane("")
+	mariadb := newTeardownObject(mariaDBGVK, "mariadb", "mariadb", instance)
+	keystone := newTeardownObject(keystoneAPIGVK, "keystone", "keystone", instance)
+	nova := newTeardownObject(novaGVK, "nova", "nova", instance)
+	otherInstance := newTeardownControlPlane("")
+	otherInstance.UID = "a8e0f7d2-6c41-4f0b-8d3e-2c9b5e7a4d61"
+	other := newTeardownObject(novaGVK, "other", "nova", otherInstance)
+	c := newTeardownClient(t, mariadb, keystone, nova, other)
+
+	// every call deletes the last phase with objects left
+	for _, deleted := range [][]*uns.Unstructured{{nova}, {nova, keystone}, {nova, keystone, mariadb}} {
+		done, err := teardown(context.TODO(), c, logf.NullLogger{}, instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			t.Fatal("expected the teardown to wait for the deletion")
+		}
+		for _, obj := range []*uns.Unstructured{mariadb, keystone, nova} {
+			expected := false
+			for _, d := range deleted {
+				expected = expected || d == obj
+			}
+			if exists := teardownObjectExists(t, c, obj); exists == expected {
+				t.Errorf("expected %s deleted: %v, exists: %v", obj.GetKind(), expected, exists)
+			}
+		}
+	}
+
+	done, err := teardown(context.TODO(), c, logf.NullLogger{}, instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !done {
+		t.Error("expected the teardown to be done")
+	}
+	if !teardownObjectExists(t, c, other) {
+		t.Error("expected the object of another ControlPlane to be kept")
+	}
+}
+
+func TestTeardownRetain(t *testing.T) {
+	instance := newTeardownControlPlane(controlplanev1beta1.DeletionPolicyRetain)
+	mariadb := newTeardownObject(mariaDBGVK, "mariadb", "mariadb", instance)
+	glance := newTeardownObject(glanceAPIGVK, "glance", "glance", instance)
+	nova := newTeardownObject(novaGVK, "nova", "nova", instance)
+	secret := newTeardownObject(secretGVK, "keystone-secret", "keystone", instance)
+	config := newTeardownObject(secretGVK, "nova-transport-url", "nova", instance)
+	claim := newOwnedClaim("mysql-db-openstack-db-0", mariadb)
+	c := newTeardownClient(t, mariadb, glance, nova, secret, config, claim)
+
+	runTeardown(t, c, logf.NullLogger{}, instance)
+
+	for _, obj := range []*uns.Unstructured{mariadb, glance, secret} {
+		if !teardownObjectExists(t, c, obj) {
+			t.Errorf("expected %s %s to be retained", obj.GetKind(), obj.GetName())
+			continue
+		}
+		current := &uns.Unstructured{}
+		current.SetGroupVersionKind(obj.GroupVersionKind())
+		if err := c.Get(context.TODO(), types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, current); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := current.GetLabels()[ownerUIDLabelSelector]; ok {
+			t.Errorf("expected %s %s to be detached", obj.GetKind(), obj.GetName())
+		}
+		if len(current.GetOwnerReferences()) != 0 {
+			t.Errorf("expected no owner references on %s %s, got %v", obj.GetKind(), obj.GetName(), current.GetOwnerReferences())
+		}
+	}
+	for _, obj := range []*uns.Unstructured{nova, config} {
+		if teardownObjectExists(t, c, obj) {
+			t.Errorf("expected %s %s to be deleted", obj.GetKind(), obj.GetName())
+		}
+	}
+	assertClaimOrphaned(t, c, claim)
+}
+
+func TestTeardownSnapshot(t *testing.T) {
+	instance := newTeardownControlPlane(controlplanev1beta1.DeletionPolicySnapshot)
+	glance := newTeardownObject(glanceAPIGVK, "glance", "glance", instance)
+	claim := newOwnedClaim("glance-data", glance)
+	c := newTeardownClient(t, glance, claim)
+
+	snapshot := &uns.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	key := types.NamespacedName{Namespace: claim.Namespace, Name: fmt.Sprintf("glance-data-%.8s", instance.UID)}
+
+	for i := 0; i < 2; i++ {
+		done, err := teardown(context.TODO(), c, logf.NullLogger{}, instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			t.Fatal("expected the teardown to wait for the snapshot")
+		}
+		if !teardownObjectExists(t, c, glance) {
+			t.Fatal("expected the GlanceAPI to be kept until the snapshot is ready")
+		}
+	}
+	if err := c.Get(context.TODO(), key, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if source, _, _ := uns.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName"); source != claim.Name {
+		t.Errorf("expected a snapshot of %s, got %s", claim.Name, source)
+	}
+
+	if err := uns.SetNestedField(snapshot.Object, true, "status", "readyToUse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update(context.TODO(), snapshot); err != nil {
+		t.Fatal(err)
+	}
+	runTeardown(t, c, logf.NullLogger{}, instance)
+	if teardownObjectExists(t, c, glance) {
+		t.Error("expected the GlanceAPI to be deleted once the snapshot is ready")
+	}
+	if err := c.Get(context.TODO(), key, snapshot); err != nil {
+		t.Errorf("expected the snapshot to be kept: %v", err)
+	}
+}
+
+func TestTeardownOrphanData(t *testing.T) {
+	instance := newTeardownControlPlane("")
+	instance.SetAnnotations(map[string]string{orphanDataAnnotation: "true"})
+	mariadb := newTeardownObject(mariaDBGVK, "mariadb", "mariadb", instance)
+	keystone := newTeardownObject(keystoneAPIGVK, "keystone", "keystone", instance)
+	claim := newOwnedClaim("mysql-db-openstack-db-0", mariadb)
+	c := newTeardownClient(t, mariadb, keystone, claim)
+
+	runTeardown(t, c, logf.NullLogger{}, instance)
+
+	for _, obj := range []*uns.Unstructured{mariadb, keystone} {
+		if teardownObjectExists(t, c, obj) {
+			t.Errorf("expected %s to be deleted", obj.GetKind())
+		}
+	}
+	assertClaimOrphaned(t, c, claim)
+}
+
+// newTeardownClient returns a fake client with the kinds of the manifests,
+// which have no types in the client, registered as unstructured. objs of
+// kinds with types get converted, the tracker can't list them otherwise.
+func newTeardownClient(t *testing.T, objs ...runtime.Object) client.Client {
+	s := runtime.NewScheme()
+	_ = scheme.AddToScheme(s)
+	kinds, err := getManifestKinds(Manifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds = append(kinds, disruptionBudgetKinds...)
+	kinds = append(kinds, volumeSnapshotGVK)
+	for _, gvk := range kinds {
+		if s.Recognizes(gvk) {
+			continue
+		}
+		s.AddKnownTypeWithName(gvk, &uns.Unstructured{})
+		s.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &uns.UnstructuredList{})
+	}
+
+	initObjs := []runtime.Object{}
+	for _, obj := range objs {
+		typed, err := toTyped(s, obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		initObjs = append(initObjs, typed)
+	}
+	return &typedUpdateClient{Client: fake.NewFakeClientWithScheme(s, initObjs...), scheme: s}
+}
+
+// typedUpdateClient converts unstructured objects of kinds with types before
+// updating them, the fake client can't list them anymore otherwise
+type typedUpdateClient struct {
+	client.Client
+	scheme *runtime.Scheme
+}
+
+func (c *typedUpdateClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	typed, err := toTyped(c.scheme, obj)
+	if err != nil {
+		return err
+	}
+	return c.Client.Update(ctx, typed, opts...)
+}
+
+func toTyped(s *runtime.Scheme, obj runtime.Object) (runtime.Object, error) {
+	u, ok := obj.(*uns.Unstructured)
+	if !ok {
+		return obj, nil
+	}
+	typed, err := s.New(u.GroupVersionKind())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := typed.(*uns.Unstructured); ok {
+		return u.DeepCopy(), nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, typed); err != nil {
+		return nil, err
+	}
+	return typed, nil
+}
+
+func newTeardownControlPlane(policy string) *controlplanev1beta1.ControlPlane {
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Name = "controlplane"
+	instance.Namespace = "openstack"
+	instance.UID = "4f9d3c2a-0d3e-4b8e-9a55-1b7c1e0a2f10"
+	instance.Spec.DeletionPolicy = policy
+	return instance
+}
+
+func newTeardownObject(gvk schema.GroupVersionKind, name, service string, instance *controlplanev1beta1.ControlPlane) *uns.Unstructured {
+	obj := &uns.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetNamespace(instance.Namespace)
+	obj.SetName(name)
+	obj.SetUID(types.UID(name + "-uid"))
+	obj.SetLabels(map[string]string{
+		ownerUIDLabelSelector:       string(instance.UID),
+		ownerNameSpaceLabelSelector: instance.Namespace,
+		ownerNameLabelSelector:      instance.Name,
+		serviceLabelSelector:        service,
+	})
+	obj.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: controlplanev1beta1.GroupVersion.String(),
+		Kind:       "ControlPlane",
+		Name:       instance.Name,
+		UID:        instance.UID,
+	}})
+	return obj
+}
+
+func newOwnedClaim(name string, owner *uns.Unstructured) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: owner.GetNamespace(),
+			OwnerReferences: []metav1.OwnerReference{{
+				APIVersion: owner.GetAPIVersion(),
+				Kind:       owner.GetKind(),
+				Name:       owner.GetName(),
+				UID:        owner.GetUID(),
+			}},
+		},
+	}
+}
+
+// runTeardown calls teardown until it is done
+func runTeardown(t *testing.T, c client.Client, log logr.Logger, instance *controlplanev1beta1.ControlPlane) {
+	for i := 0; i <= len(rolloutPhases); i++ {
+		done, err := teardown(context.TODO(), c, log, instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if done {
+			return
+		}
+	}
+	t.Fatal("expected the teardown to be done")
+}
+
+func teardownObjectExists(t *testing.T, c client.Client, obj *uns.Unstructured) bool {
+	current := &uns.Unstructured{}
+	current.SetGroupVersionKind(obj.GroupVersionKind())
+	err := c.Get(context.TODO(), types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}, current)
+	if k8s_errors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return true
+}
+
+func assertClaimOrphaned(t *testing.T, c client.Client, claim *corev1.PersistentVolumeClaim) {
+	current := &corev1.PersistentVolumeClaim{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Namespace: claim.Namespace, Name: claim.Name}, current); err != nil {
+		t.Fatal(err)
+	}
+	if len(current.OwnerReferences) != 0 {
+		t.Errorf("expected the claim %s to be orphaned, got owners %v", claim.Name, current.OwnerReferences)
+	}
+}
@@ -0,0 +1,227 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	bindatautil "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/bindata_util"
+)
+
+// TestTemplateRenderDataKeys checks that the templates only reference fields
+// of the render data and that every top level field is used
+func TestTemplateRenderDataKeys(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	setDefaults(instance)
+
+	values, err := getRenderData(context.TODO(), nil, instance)
+	if err != nil {
+		t.Fatalf("getRenderData: %v", err)
+	}
+	data := makeRenderData(values)
+
+	used := map[string][]string{}
+	for _, dir := range append(serviceManifestDirs, "exposure", "pdb") {
+		fields, err := bindatautil.TemplateFields(Manifests, dir, &data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for field, paths := range fields {
+			used[field] = append(used[field], paths...)
+		}
+	}
+
+	missing, unused := bindatautil.CheckRenderData(used, &data)
+	for _, field := range missing {
+		t.Errorf("%v reference %s which is not part of the render data", used[field], field)
+	}
+	for _, field := range unused {
+		t.Errorf("%s is part of the render data but not referenced by any template", field)
+	}
+}
+
+// TestRenderWithoutExposure checks that nothing gets exposed by default, the
+// Route API only exists on OpenShift
+func TestRenderWithoutExposure(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	setDefaults(instance)
+
+	objs, err := RenderControlPlane(context.TODO(), nil, instance, nil)
+	if err != nil {
+		t.Fatalf("RenderControlPlane: %v", err)
+	}
+	for _, obj := range objs {
+		switch obj.GetKind() {
+		case "Route", "Ingress":
+			t.Errorf("expected no exposure, got %s %s", obj.GetKind(), obj.GetName())
+		}
+	}
+}
+
+// TestRenderConfigOverwriteKeys checks that the files of the default config
+// overwrites stay strings in the ConfigMap, and that invalid keys get rejected
+func TestRenderConfigOverwriteKeys(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	instance.Spec.Cinder.DefaultConfigOverwrite = map[string]string{"on": "a", "1.0": "b", "policy.yaml": "c"}
+	setDefaults(instance)
+	if err := validateServiceConfigs(instance); err != nil {
+		t.Fatal(err)
+	}
+
+	objs, err := RenderControlPlane(context.TODO(), nil, instance, nil)
+	if err != nil {
+		t.Fatalf("RenderControlPlane: %v", err)
+	}
+	var data map[string]string
+	for _, obj := range objs {
+		if obj.GetKind() == "ConfigMap" && obj.GetName() == "cinder-config-custom" {
+			data, _, _ = uns.NestedStringMap(obj.Object, "data")
+		}
+	}
+	for file, content := range instance.Spec.Cinder.DefaultConfigOverwrite {
+		if strings.TrimSpace(data[file]) != content {
+			t.Errorf("expected %s in the cinder config, got %v", file, data)
+		}
+	}
+
+	instance.Spec.Cinder.DefaultConfigOverwrite = map[string]string{"../cinder.conf": ""}
+	if err := validateServiceConfigs(instance); err == nil {
+		t.Error("expected an invalid file name to be rejected")
+	}
+}
+
+// TestRenderPlacement checks that the placement of a service ends up in the
+// spec of its child resource and that services without one get none
+func TestRenderPlacement(t *testing.T) {
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	instance.Spec.Keystone.PodPlacement = controlplanev1beta1.PodPlacementSpec{
+		NodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+		Tolerations:  []corev1.Toleration{{Key: "infra", Operator: corev1.TolerationOpExists}},
+	}
+	setDefaults(instance)
+
+	objs, err := RenderControlPlane(context.TODO(), nil, instance, nil)
+	if err != nil {
+		t.Fatalf("RenderControlPlane: %v", err)
+	}
+	for _, obj := range objs {
+		switch obj.GetKind() {
+		case "KeystoneAPI":
+			nodeSelector, _, _ := uns.NestedStringMap(obj.Object, "spec", "nodeSelector")
+			if !reflect.DeepEqual(nodeSelector, instance.Spec.Keystone.PodPlacement.NodeSelector) {
+				t.Errorf("expected KeystoneAPI node selector %v, got %v", instance.Spec.Keystone.PodPlacement.NodeSelector, nodeSelector)
+			}
+			tolerations, _, _ := uns.NestedSlice(obj.Object, "spec", "tolerations")
+			if len(tolerations) != 1 {
+				t.Errorf("expected a KeystoneAPI toleration, got %v", tolerations)
+			}
+		case "GlanceAPI":
+			for _, field := range []string{"nodeSelector", "tolerations", "affinity", "topologySpreadConstraints"} {
+				if _, ok, _ := uns.NestedFieldNoCopy(obj.Object, "spec", field); ok {
+					t.Errorf("expected no %s on GlanceAPI", field)
+				}
+			}
+		}
+	}
+}
+
+// TestRenderResources checks that the resources of the Nova and Cinder
+// components win over the ones of the service, and that no resources get
+// rendered when none are set
+func TestRenderResources(t *testing.T) {
+	requests := func(name corev1.ResourceName, quantity string) *corev1.ResourceRequirements {
+		return &corev1.ResourceRequirements{Requests: corev1.ResourceList{name: resource.MustParse(quantity)}}
+	}
+	instance := &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	instance.Spec.Nova.Resources = *requests(corev1.ResourceCPU, "100m")
+	instance.Spec.Nova.NovaAPIResources = requests(corev1.ResourceCPU, "2")
+	instance.Spec.Cinder.Resources = *requests(corev1.ResourceMemory, "1Gi")
+	instance.Spec.Cinder.CinderVolumeResources = requests(corev1.ResourceMemory, "4Gi")
+	setDefaults(instance)
+
+	objs, err := RenderControlPlane(context.TODO(), nil, instance, nil)
+	if err != nil {
+		t.Fatalf("RenderControlPlane: %v", err)
+	}
+	tests := []struct {
+		kind     string
+		fields   []string
+		resource string
+		expected string
+	}{
+		{kind: "Nova", fields: []string{"spec", "novaAPIResources"}, resource: "cpu", expected: "2"},
+		{kind: "Nova", fields: []string{"spec", "novaSchedulerResources"}, resource: "cpu", expected: "100m"},
+		{kind: "Nova", fields: []string{"spec", "novaConductorResources"}, resource: "cpu", expected: "100m"},
+		{kind: "Cinder", fields: []string{"spec", "cinderAPIResources"}, resource: "memory", expected: "1Gi"},
+		{kind: "Cinder", fields: []string{"spec", "cinderBackupResources"}, resource: "memory", expected: "1Gi"},
+	}
+	for _, tt := range tests {
+		obj := findRenderedKind(objs, tt.kind)
+		if obj == nil {
+			t.Fatalf("no %s rendered", tt.kind)
+		}
+		value, _, _ := uns.NestedString(obj.Object, append(tt.fields, "requests", tt.resource)...)
+		if value != tt.expected {
+			t.Errorf("%s %v: %s request %q, expected %q", tt.kind, tt.fields, tt.resource, value, tt.expected)
+		}
+	}
+	cinder := findRenderedKind(objs, "Cinder")
+	volumes, _, _ := uns.NestedSlice(cinder.Object, "spec", "cinderVolumes")
+	if value, _, _ := uns.NestedString(volumes[0].(map[string]interface{}), "cinderVolumeResources", "requests", "memory"); value != "4Gi" {
+		t.Errorf("cinder volume memory request %q, expected 4Gi", value)
+	}
+
+	instance = &controlplanev1beta1.ControlPlane{}
+	instance.Namespace = "openstack"
+	setDefaults(instance)
+	objs, err = RenderControlPlane(context.TODO(), nil, instance, nil)
+	if err != nil {
+		t.Fatalf("RenderControlPlane: %v", err)
+	}
+	for _, obj := range objs {
+		// the component resources are nested in the cells and volumes
+		j, err := obj.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(j), `Resources":`) || strings.Contains(string(j), `"resources":`) {
+			t.Errorf("expected no resources on %s %s", obj.GetKind(), obj.GetName())
+		}
+	}
+}
+
+func findRenderedKind(objs []*uns.Unstructured, kind string) *uns.Unstructured {
+	for _, obj := range objs {
+		if obj.GetKind() == kind {
+			return obj
+		}
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+const (
+	// host of the MariaDB service created by the mariadb operator
+	mariadbHostname = "mariadb"
+	// image used to copy the dumps from and to S3-compatible object storage
+	backupS3Image = "docker.io/amazon/aws-cli:2.0.30"
+	// where the dump volume gets mounted in the backup and restore jobs
+	backupMountPath = "/backup"
+	// number of retries of a backup or restore job
+	backupBackoffLimit int32 = 2
+)
+
+// ControlPlaneBackupReconciler reconciles a ControlPlaneBackup object
+type ControlPlaneBackupReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=controlplane.openstack.org,resources=controlplanebackups,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=controlplane.openstack.org,resources=controlplanebackups/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile ControlPlaneBackup requests
+func (r *ControlPlaneBackupReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	_ = context.Background()
+	log := r.Log.WithValues("controlplanebackup", req.NamespacedName)
+
+	instance := &controlplanev1beta1.ControlPlaneBackup{}
+	err := r.Client.Get(context.TODO(), req.NamespacedName, instance)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	// a backup runs once, the job of a finished backup may already be gone
+	if instance.Status.Phase == controlplanev1beta1.CommandPhaseSucceeded || instance.Status.Phase == controlplanev1beta1.CommandPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+	oldStatus := instance.Status.DeepCopy()
+
+	if err := validateBackupTarget(&instance.Spec.Target); err != nil {
+		log.Error(err, "Invalid backup target")
+		instance.Status.Phase = controlplanev1beta1.CommandPhaseFailed
+		return ctrl.Result{}, r.updateStatus(instance, oldStatus)
+	}
+
+	controlPlane := &controlplanev1beta1.ControlPlane{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: instance.Spec.ControlPlane, Namespace: instance.Namespace}, controlPlane)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			log.Info("ControlPlane not found, requeue", "ControlPlane", instance.Spec.ControlPlane)
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	job := &batchv1.Job{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: getBackupJobName(instance), Namespace: instance.Namespace}, job)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	if k8s_errors.IsNotFound(err) {
+		job = getBackupJob(instance, controlPlane)
+		if err := controllerutil.SetControllerReference(instance, job, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Creating backup job", "Job", job.Name)
+		if err := r.Client.Create(context.TODO(), job); err != nil {
+			return ctrl.Result{}, err
+		}
+		instance.Status = controlplanev1beta1.ControlPlaneBackupStatus{
+			Phase:    controlplanev1beta1.CommandPhasePending,
+			Location: getBackupLocation(instance),
+		}
+		return ctrl.Result{}, r.updateStatus(instance, oldStatus)
+	}
+
+	instance.Status.Phase, instance.Status.CompletionTime = getJobPhase(job)
+	return ctrl.Result{}, r.updateStatus(instance, oldStatus)
+}
+
+// SetupWithManager func
+func (r *ControlPlaneBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&controlplanev1beta1.ControlPlaneBackup{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+// updateStatus writes the status if it differs from oldStatus
+func (r *ControlPlaneBackupReconciler) updateStatus(instance *controlplanev1beta1.ControlPlaneBackup, oldStatus *controlplanev1beta1.ControlPlaneBackupStatus) error {
+	if reflect.DeepEqual(oldStatus, &instance.Status) {
+		return nil
+	}
+	return r.Client.Status().Update(context.TODO(), instance)
+}
+
+// validateBackupTarget checks that exactly one target is set
+func validateBackupTarget(target *controlplanev1beta1.BackupTarget) error {
+	if (target.PersistentVolumeClaim == "") == (target.S3 == nil) {
+		return fmt.Errorf("exactly one of persistentVolumeClaim and s3 has to be set")
+	}
+	return nil
+}
+
+func getBackupJobName(instance *controlplanev1beta1.ControlPlaneBackup) string {
+	return instance.Name + "-backup"
+}
+
+// getBackupFile returns the file name of the dump of a backup
+func getBackupFile(instance *controlplanev1beta1.ControlPlaneBackup) string {
+	return instance.Name + ".sql.gz"
+}
+
+// getBackupLocation returns the file name of the dump in the volume claim or
+// its URL in the bucket
+func getBackupLocation(instance *controlplanev1beta1.ControlPlaneBackup) string {
+	if s3 := instance.Spec.Target.S3; s3 != nil {
+		return "s3://" + path.Join(s3.Bucket, s3.Prefix, getBackupFile(instance))
+	}
+	return getBackupFile(instance)
+}
+
+// getBackupJob returns a job dumping all databases of the MariaDB. Dumps to
+// S3 are written to an emptyDir first and uploaded by a second container, as
+// the MariaDB image has no S3 client.
+func getBackupJob(instance *controlplanev1beta1.ControlPlaneBackup, controlPlane *controlplanev1beta1.ControlPlane) *batchv1.Job {
+	file := path.Join(backupMountPath, getBackupFile(instance))
+	dump := getMariaDBContainer("dump", getServiceSecrets(&controlPlane.Spec).MariaDB, []string{
+		"set -o pipefail",
+		fmt.Sprintf("mysqldump -h %s -u root --all-databases --single-transaction --routines --events | gzip > %s.tmp", mariadbHostname, file),
+		fmt.Sprintf("mv %s.tmp %s", file, file),
+	})
+
+	job := getBackupRestoreJob(getBackupJobName(instance), instance.Namespace, &instance.Spec.Target)
+	podSpec := &job.Spec.Template.Spec
+	if s3 := instance.Spec.Target.S3; s3 != nil {
+		podSpec.InitContainers = []corev1.Container{dump}
+		podSpec.Containers = []corev1.Container{
+			getS3Container("upload", s3, fmt.Sprintf("aws --endpoint-url %s s3 cp %s %s",
+				shellQuote(s3.Endpoint), file, shellQuote(getBackupLocation(instance)))),
+		}
+	} else {
+		podSpec.Containers = []corev1.Container{dump}
+	}
+	return job
+}
+
+// getBackupRestoreJob returns a job without containers which mounts the
+// volume the dump gets written to or read from
+func getBackupRestoreJob(name string, namespace string, target *controlplanev1beta1.BackupTarget) *batchv1.Job {
+	backoffLimit := backupBackoffLimit
+
+	volume := corev1.Volume{Name: "backup"}
+	if target.S3 != nil {
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+	} else {
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: target.PersistentVolumeClaim,
+		}
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": "controlplanebackup",
+					},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Volumes:       []corev1.Volume{volume},
+				},
+			},
+		},
+	}
+}
+
+// getMariaDBContainer returns a container running the script with the
+// MariaDB image and the root password from the MariaDB secret
+func getMariaDBContainer(name string, secret string, script []string) corev1.Container {
+	return corev1.Container{
+		Name:    name,
+		Image:   getServiceImages().MariaDB,
+		Command: []string{"/bin/bash", "-c", strings.Join(append([]string{"set -e"}, script...), "\n")},
+		Env: []corev1.EnvVar{
+			{
+				// read by the mysql clients, keeps the password off the command line
+				Name: "MYSQL_PWD",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: secret,
+						},
+						Key: "DbRootPassword",
+					},
+				},
+			},
+		},
+		VolumeMounts: getBackupVolumeMounts(),
+	}
+}
+
+// getS3Container returns a container running the command with the AWS CLI
+// and the credentials of the bucket
+func getS3Container(name string, s3 *controlplanev1beta1.S3Target, command string) corev1.Container {
+	env := []corev1.EnvVar{}
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		env = append(env, corev1.EnvVar{
+			Name: key,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: s3.CredentialsSecret,
+					},
+					Key: key,
+				},
+			},
+		})
+	}
+	return corev1.Container{
+		Name:         name,
+		Image:        backupS3Image,
+		Command:      []string{"/bin/bash", "-c", command},
+		Env:          env,
+		VolumeMounts: getBackupVolumeMounts(),
+	}
+}
+
+func getBackupVolumeMounts() []corev1.VolumeMount {
+	return []corev1.VolumeMount{
+		{
+			Name:      "backup",
+			MountPath: backupMountPath,
+		},
+	}
+}
@@ -0,0 +1,358 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+// annotation on the ControlPlane with the name of the ControlPlaneRestore
+// its API services are scaled down for
+const quiesceAnnotation = "controlplane.openstack.org/quiesced-by"
+
+// ControlPlaneRestoreReconciler reconciles a ControlPlaneRestore object
+type ControlPlaneRestoreReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=controlplane.openstack.org,resources=controlplanerestores,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=controlplane.openstack.org,resources=controlplanerestores/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile ControlPlaneRestore requests. The restore moves from Quiescing,
+// where the ControlPlane scales its API services to 0, to Restoring, where a
+// job loads the dump, to Resuming, where the ControlPlane scales them up again.
+func (r *ControlPlaneRestoreReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	_ = context.Background()
+	log := r.Log.WithValues("controlplanerestore", req.NamespacedName)
+
+	instance := &controlplanev1beta1.ControlPlaneRestore{}
+	err := r.Client.Get(context.TODO(), req.NamespacedName, instance)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	if instance.Status.Phase == controlplanev1beta1.CommandPhaseSucceeded || instance.Status.Phase == controlplanev1beta1.CommandPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+	oldStatus := instance.Status.DeepCopy()
+
+	backup := &controlplanev1beta1.ControlPlaneBackup{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: instance.Spec.Backup, Namespace: instance.Namespace}, backup)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	if k8s_errors.IsNotFound(err) || backup.Status.Phase != controlplanev1beta1.CommandPhaseSucceeded {
+		log.Info("Backup not found or not succeeded yet, requeue", "Backup", instance.Spec.Backup)
+		instance.Status.Phase = controlplanev1beta1.CommandPhasePending
+		return ctrl.Result{RequeueAfter: time.Second * 10}, r.updateStatus(instance, oldStatus)
+	}
+
+	controlPlane := &controlplanev1beta1.ControlPlane{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: backup.Spec.ControlPlane, Namespace: instance.Namespace}, controlPlane)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			log.Info("ControlPlane not found, requeue", "ControlPlane", backup.Spec.ControlPlane)
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	switch instance.Status.Phase {
+	case "", controlplanev1beta1.CommandPhasePending:
+		if quiescedBy := controlPlane.GetAnnotations()[quiesceAnnotation]; quiescedBy != "" && quiescedBy != instance.Name {
+			log.Info("ControlPlane is quiesced by another restore, requeue", "ControlPlaneRestore", quiescedBy)
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		// The phase has to be Quiescing before the annotation gets set,
+		// otherwise the ControlPlane takes the restore for inactive and
+		// removes the annotation again
+		instance.Status.Phase = controlplanev1beta1.RestorePhaseQuiescing
+		return ctrl.Result{RequeueAfter: time.Second * 10}, r.updateStatus(instance, oldStatus)
+
+	case controlplanev1beta1.RestorePhaseQuiescing:
+		if quiescedBy := controlPlane.GetAnnotations()[quiesceAnnotation]; quiescedBy != "" && quiescedBy != instance.Name {
+			log.Info("ControlPlane is quiesced by another restore, requeue", "ControlPlaneRestore", quiescedBy)
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		if controlPlane.GetAnnotations()[quiesceAnnotation] == "" {
+			log.Info("Scaling down the API services", "ControlPlane", controlPlane.Name)
+			if err := setQuiesceAnnotation(r.Client, controlPlane, instance.Name); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		if !controlPlane.Status.Quiesced {
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		job := getRestoreJob(instance, backup, controlPlane)
+		if err := controllerutil.SetControllerReference(instance, job, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Creating restore job", "Job", job.Name)
+		if err := r.Client.Create(context.TODO(), job); err != nil && !k8s_errors.IsAlreadyExists(err) {
+			return ctrl.Result{}, err
+		}
+		instance.Status.Phase = controlplanev1beta1.RestorePhaseRestoring
+		return ctrl.Result{}, r.updateStatus(instance, oldStatus)
+
+	case controlplanev1beta1.RestorePhaseRestoring:
+		job := &batchv1.Job{}
+		err := r.Client.Get(context.TODO(), types.NamespacedName{Name: getRestoreJobName(instance), Namespace: instance.Namespace}, job)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		// a job deleted before it finished can't tell if the dump got loaded,
+		// the services still have to be scaled up again
+		jobPhase := controlplanev1beta1.CommandPhaseFailed
+		if err == nil {
+			jobPhase, _ = getJobPhase(job)
+			if jobPhase != controlplanev1beta1.CommandPhaseSucceeded && jobPhase != controlplanev1beta1.CommandPhaseFailed {
+				return ctrl.Result{}, nil
+			}
+		}
+		log.Info("Restore job finished, scaling up the API services", "Job", getRestoreJobName(instance), "Result", jobPhase)
+		if err := setQuiesceAnnotation(r.Client, controlPlane, ""); err != nil {
+			return ctrl.Result{}, err
+		}
+		instance.Status.JobPhase = jobPhase
+		instance.Status.Phase = controlplanev1beta1.RestorePhaseResuming
+		return ctrl.Result{RequeueAfter: time.Second * 10}, r.updateStatus(instance, oldStatus)
+
+	case controlplanev1beta1.RestorePhaseResuming:
+		if controlPlane.Status.Quiesced || !controlPlane.Status.Ready {
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		now := metav1.Now()
+		instance.Status.Phase = instance.Status.JobPhase
+		instance.Status.CompletionTime = &now
+		return ctrl.Result{}, r.updateStatus(instance, oldStatus)
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager func
+func (r *ControlPlaneRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&controlplanev1beta1.ControlPlaneRestore{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+// updateStatus writes the status if it differs from oldStatus
+func (r *ControlPlaneRestoreReconciler) updateStatus(instance *controlplanev1beta1.ControlPlaneRestore, oldStatus *controlplanev1beta1.ControlPlaneRestoreStatus) error {
+	if reflect.DeepEqual(oldStatus, &instance.Status) {
+		return nil
+	}
+	return r.Client.Status().Update(context.TODO(), instance)
+}
+
+// setQuiesceAnnotation sets or, if restore is empty, removes the quiesce
+// annotation of the ControlPlane
+func setQuiesceAnnotation(c client.Client, controlPlane *controlplanev1beta1.ControlPlane, restore string) error {
+	annotations := controlPlane.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if annotations[quiesceAnnotation] == restore {
+		return nil
+	}
+	if restore == "" {
+		delete(annotations, quiesceAnnotation)
+	} else {
+		annotations[quiesceAnnotation] = restore
+	}
+	controlPlane.SetAnnotations(annotations)
+	return c.Update(context.TODO(), controlPlane)
+}
+
+// isRestoreActive checks if the ControlPlaneRestore quiescing a ControlPlane
+// still needs the API services to be scaled down
+func isRestoreActive(ctx context.Context, c client.Client, namespace string, name string) (bool, error) {
+	restore := &controlplanev1beta1.ControlPlaneRestore{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, restore)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	switch restore.Status.Phase {
+	case "", controlplanev1beta1.CommandPhasePending, controlplanev1beta1.RestorePhaseQuiescing, controlplanev1beta1.RestorePhaseRestoring:
+		return true, nil
+	}
+	return false, nil
+}
+
+// quiescedServices - services whose pods are gone once the ControlPlane is
+// quiesced
+var quiescedServices = map[string]bool{
+	"keystone":  true,
+	"glance":    true,
+	"placement": true,
+	"neutron":   true,
+	"nova":      true,
+	"cinder":    true,
+}
+
+// maxOwnerDepth - owner references followed from a pod to a rendered object,
+// e.g. Pod, ReplicaSet, Deployment, custom resource of the child operator
+const maxOwnerDepth = 4
+
+// getRunningServicePods returns the pods of the quiesced services which
+// haven't terminated yet. The child operators report readiness at 0 replicas
+// before the pods are gone, so the pods get looked up through the owner
+// references to the rendered objects.
+func getRunningServicePods(ctx context.Context, c client.Client, objs []*uns.Unstructured) ([]string, error) {
+	owners := map[string]bool{}
+	namespaces := map[string]bool{}
+	for _, obj := range objs {
+		if quiescedServices[obj.GetLabels()[serviceLabelSelector]] {
+			owners[getOwnerKey(obj.GetNamespace(), obj.GetKind(), obj.GetName())] = true
+			namespaces[obj.GetNamespace()] = true
+		}
+	}
+
+	running := []string{}
+	for namespace := range namespaces {
+		// owner references of the workloads between the pods and the
+		// rendered objects
+		ownerRefs := map[string][]metav1.OwnerReference{}
+		for _, kind := range []string{"ReplicaSet", "Deployment", "StatefulSet"} {
+			list := &uns.UnstructuredList{}
+			list.SetGroupVersionKind(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: kind + "List"})
+			if err := c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+				return nil, err
+			}
+			for _, item := range list.Items {
+				ownerRefs[getOwnerKey(namespace, kind, item.GetName())] = item.GetOwnerReferences()
+			}
+		}
+
+		pods := &uns.UnstructuredList{}
+		pods.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "PodList"})
+		if err := c.List(ctx, pods, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		for _, pod := range pods.Items {
+			phase, _, _ := uns.NestedString(pod.Object, "status", "phase")
+			if phase == string(corev1.PodSucceeded) || phase == string(corev1.PodFailed) {
+				continue
+			}
+			if isOwnedBy(namespace, pod.GetOwnerReferences(), ownerRefs, owners, maxOwnerDepth) {
+				running = append(running, namespace+"/"+pod.GetName())
+			}
+		}
+	}
+	sort.Strings(running)
+	return running, nil
+}
+
+// isOwnedBy follows the owner references up to depth levels and checks if
+// one of them is in owners
+func isOwnedBy(namespace string, refs []metav1.OwnerReference, ownerRefs map[string][]metav1.OwnerReference, owners map[string]bool, depth int) bool {
+	if depth == 0 {
+		return false
+	}
+	for _, ref := range refs {
+		key := getOwnerKey(namespace, ref.Kind, ref.Name)
+		if owners[key] || isOwnedBy(namespace, ownerRefs[key], ownerRefs, owners, depth-1) {
+			return true
+		}
+	}
+	return false
+}
+
+func getOwnerKey(namespace string, kind string, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// quiesceServices scales the API services to 0. MariaDB and Interconnect
+// keep running, the restore needs the database.
+func quiesceServices(spec *controlplanev1beta1.ControlPlaneSpec) {
+	none := 0
+	spec.Keystone.Replicas = 0
+	spec.Glance.Replicas = 0
+	spec.Placement.Replicas = 0
+	spec.Neutron.Replicas = 0
+	spec.Nova.NovaAPIReplicas = 0
+	spec.Nova.NovaSchedulerReplicas = 0
+	spec.Nova.NovaConductorReplicas = 0
+	spec.Nova.NovaCell1ConductorReplicas = &none
+	spec.Nova.NovaMetadataReplicas = 0
+	spec.Nova.NovaNoVNCProxyReplicas = 0
+	spec.Cinder.CinderAPIReplicas = 0
+	spec.Cinder.CinderSchedulerReplicas = 0
+	spec.Cinder.CinderBackupReplicas = 0
+	spec.Cinder.CinderVolumeReplicas = 0
+}
+
+func getRestoreJobName(instance *controlplanev1beta1.ControlPlaneRestore) string {
+	return instance.Name + "-restore"
+}
+
+// skipMySQLDatabase - awk program dropping the mysql database from a dump of
+// all databases. Its grant tables hold the passwords at the time of the
+// backup, loading them would revert rotated passwords.
+const skipMySQLDatabase = "/^-- Current Database: /{skip = ($4 == \"`mysql`\")} !skip"
+
+// getRestoreJob returns a job loading the dump of the backup into the
+// MariaDB, dumps on S3 get downloaded to an emptyDir first. The mysql
+// database is left out, the users and passwords stay the current ones.
+func getRestoreJob(instance *controlplanev1beta1.ControlPlaneRestore, backup *controlplanev1beta1.ControlPlaneBackup, controlPlane *controlplanev1beta1.ControlPlane) *batchv1.Job {
+	file := path.Join(backupMountPath, getBackupFile(backup))
+	load := getMariaDBContainer("restore", getServiceSecrets(&controlPlane.Spec).MariaDB, []string{
+		"set -o pipefail",
+		fmt.Sprintf("gunzip -c %s | awk %s | mysql -h %s -u root", file, shellQuote(skipMySQLDatabase), mariadbHostname),
+	})
+
+	job := getBackupRestoreJob(getRestoreJobName(instance), instance.Namespace, &backup.Spec.Target)
+	podSpec := &job.Spec.Template.Spec
+	if s3 := backup.Spec.Target.S3; s3 != nil {
+		podSpec.InitContainers = []corev1.Container{
+			getS3Container("download", s3, fmt.Sprintf("aws --endpoint-url %s s3 cp %s %s",
+				shellQuote(s3.Endpoint), shellQuote(backup.Status.Location), file)),
+		}
+	}
+	podSpec.Containers = []corev1.Container{load}
+	return job
+}
@@ -0,0 +1,187 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+func TestGetRunningServicePods(t *testing.T) {
+	keystone := &uns.Unstructured{}
+	keystone.SetAPIVersion("keystone.openstack.org/v1")
+	keystone.SetKind("KeystoneAPI")
+	keystone.SetName("keystone")
+	keystone.SetNamespace("openstack")
+	setServiceLabel([]*uns.Unstructured{keystone}, "keystone")
+	mariadb := &uns.Unstructured{}
+	mariadb.SetAPIVersion("mariadb.openstack.org/v1")
+	mariadb.SetKind("MariaDB")
+	mariadb.SetName("mariadb")
+	mariadb.SetNamespace("openstack")
+	setServiceLabel([]*uns.Unstructured{mariadb}, "mariadb")
+
+	ownedBy := func(kind string, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Kind: kind, Name: name}}
+	}
+	pod := func(name string, phase corev1.PodPhase, owners []metav1.OwnerReference) runtime.Object {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "openstack", OwnerReferences: owners},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme,
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "keystone-api", Namespace: "openstack", OwnerReferences: ownedBy("KeystoneAPI", "keystone")}},
+		&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "keystone-api-1", Namespace: "openstack", OwnerReferences: ownedBy("Deployment", "keystone-api")}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "mariadb", Namespace: "openstack", OwnerReferences: ownedBy("MariaDB", "mariadb")}},
+		pod("keystone-api-1-a", corev1.PodRunning, ownedBy("ReplicaSet", "keystone-api-1")),
+		pod("keystone-api-1-b", corev1.PodSucceeded, ownedBy("ReplicaSet", "keystone-api-1")),
+		pod("keystone-bootstrap", corev1.PodPending, ownedBy("KeystoneAPI", "keystone")),
+		pod("mariadb-0", corev1.PodRunning, ownedBy("StatefulSet", "mariadb")),
+		pod("unowned", corev1.PodRunning, nil),
+	)
+
+	running, err := getRunningServicePods(context.TODO(), c, []*uns.Unstructured{keystone, mariadb})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"openstack/keystone-api-1-a", "openstack/keystone-bootstrap"}
+	if !reflect.DeepEqual(running, expected) {
+		t.Errorf("expected running pods %v, got %v", expected, running)
+	}
+}
+
+func TestIsRestoreActive(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = scheme.AddToScheme(s)
+	_ = controlplanev1beta1.AddToScheme(s)
+	for phase, active := range map[string]bool{
+		"":                                      true,
+		controlplanev1beta1.CommandPhasePending: true,
+		controlplanev1beta1.RestorePhaseQuiescing: true,
+		controlplanev1beta1.RestorePhaseRestoring: true,
+		controlplanev1beta1.RestorePhaseResuming:  false,
+		controlplanev1beta1.CommandPhaseSucceeded: false,
+	} {
+		restore := &controlplanev1beta1.ControlPlaneRestore{ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "openstack"}}
+		restore.Status.Phase = phase
+		c := fake.NewFakeClientWithScheme(s, restore)
+
+		got, err := isRestoreActive(context.TODO(), c, "openstack", "restore")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != active {
+			t.Errorf("phase %q: expected active %v, got %v", phase, active, got)
+		}
+	}
+}
+
+func TestReconcileRestoreJobNotFound(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = scheme.AddToScheme(s)
+	_ = controlplanev1beta1.AddToScheme(s)
+
+	controlPlane := &controlplanev1beta1.ControlPlane{ObjectMeta: metav1.ObjectMeta{
+		Name:        "controlplane",
+		Namespace:   "openstack",
+		Annotations: map[string]string{quiesceAnnotation: "restore"},
+	}}
+	backup := &controlplanev1beta1.ControlPlaneBackup{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "openstack"}}
+	backup.Spec.ControlPlane = controlPlane.Name
+	backup.Status.Phase = controlplanev1beta1.CommandPhaseSucceeded
+	restore := &controlplanev1beta1.ControlPlaneRestore{ObjectMeta: metav1.ObjectMeta{Name: "restore", Namespace: "openstack"}}
+	restore.Spec.Backup = backup.Name
+	restore.Status.Phase = controlplanev1beta1.RestorePhaseRestoring
+	c := fake.NewFakeClientWithScheme(s, controlPlane, backup, restore)
+	r := &ControlPlaneRestoreReconciler{Client: c, Log: logf.NullLogger{}, Scheme: s}
+
+	// the restore job got deleted before it finished
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: restore.Name, Namespace: restore.Namespace}}
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.TODO(), req.NamespacedName, restore); err != nil {
+		t.Fatal(err)
+	}
+	if restore.Status.Phase != controlplanev1beta1.RestorePhaseResuming || restore.Status.JobPhase != controlplanev1beta1.CommandPhaseFailed {
+		t.Errorf("expected the restore to resume as failed, got %+v", restore.Status)
+	}
+	current := &controlplanev1beta1.ControlPlane{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: controlPlane.Name, Namespace: controlPlane.Namespace}, current); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := current.GetAnnotations()[quiesceAnnotation]; ok {
+		t.Error("expected the ControlPlane to be resumed")
+	}
+}
+
+func TestReconcileBackupUnchangedStatus(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = scheme.AddToScheme(s)
+	_ = controlplanev1beta1.AddToScheme(s)
+
+	controlPlane := &controlplanev1beta1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "controlplane", Namespace: "openstack"}}
+	backup := &controlplanev1beta1.ControlPlaneBackup{ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "openstack"}}
+	backup.Spec.ControlPlane = controlPlane.Name
+	backup.Spec.Target.PersistentVolumeClaim = "backups"
+	c := fake.NewFakeClientWithScheme(s, controlPlane, backup)
+	r := &ControlPlaneBackupReconciler{Client: c, Log: logf.NullLogger{}, Scheme: s}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: backup.Name, Namespace: backup.Namespace}}
+
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: getBackupJobName(backup), Namespace: backup.Namespace}, &batchv1.Job{}); err != nil {
+		t.Fatalf("expected a backup job: %v", err)
+	}
+	if err := c.Get(context.TODO(), req.NamespacedName, backup); err != nil {
+		t.Fatal(err)
+	}
+	resourceVersion := backup.ResourceVersion
+
+	// the job is still running
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Get(context.TODO(), req.NamespacedName, backup); err != nil {
+		t.Fatal(err)
+	}
+	if backup.Status.Phase != controlplanev1beta1.CommandPhasePending {
+		t.Errorf("expected the backup to be pending, got %s", backup.Status.Phase)
+	}
+	if backup.ResourceVersion != resourceVersion {
+		t.Errorf("expected no status update, resource version %s changed to %s", resourceVersion, backup.ResourceVersion)
+	}
+}
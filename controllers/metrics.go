@@ -0,0 +1,193 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	bindatautil "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/bindata_util"
+)
+
+var (
+	servicesDesired = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "openstack_controlplane_services_desired",
+			Help: "Number of services of the control plane",
+		},
+		[]string{"namespace", "controlplane"},
+	)
+	servicesReady = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "openstack_controlplane_services_ready",
+			Help: "Number of services of the control plane whose objects are ready",
+		},
+		[]string{"namespace", "controlplane"},
+	)
+	objectsApplied = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "openstack_controlplane_objects_applied_total",
+			Help: "Number of rendered objects applied, by GVK and result: created, updated, unchanged or failed",
+		},
+		[]string{"namespace", "controlplane", "group", "version", "kind", "result"},
+	)
+	renderErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "openstack_controlplane_render_errors_total",
+			Help: "Number of reconciles which failed to validate or render the manifests",
+		},
+		[]string{"namespace", "controlplane"},
+	)
+	rolloutPhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "openstack_controlplane_rollout_phase_duration_seconds",
+			Help:    "Time from entering a rollout phase until its objects were ready",
+			Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+		},
+		[]string{"namespace", "controlplane", "phase"},
+	)
+	secretAge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "openstack_controlplane_secret_age_seconds",
+			Help: "Time since the passwords of a service secret were created or last rotated",
+		},
+		[]string{"namespace", "controlplane", "secret"},
+	)
+)
+
+// labelDeleter - metric vector whose series can be deleted by label values
+type labelDeleter interface {
+	DeleteLabelValues(lvs ...string) bool
+}
+
+// trackedSeries - series of a metric vector, by joined label values
+type trackedSeries struct {
+	vec    labelDeleter
+	labels string
+}
+
+var (
+	// label values of the series recorded for each ControlPlane, so they can
+	// be deleted with it. The client library can't delete series by a partial
+	// label match.
+	controlPlaneSeries     = map[types.NamespacedName]map[trackedSeries][]string{}
+	controlPlaneSeriesLock sync.Mutex
+)
+
+func init() {
+	// Register the metrics with the controller-runtime registry served on the metrics endpoint
+	metrics.Registry.MustRegister(
+		servicesDesired,
+		servicesReady,
+		objectsApplied,
+		renderErrors,
+		rolloutPhaseDuration,
+		secretAge,
+	)
+}
+
+// trackLabels remembers the series of vec with the label values, the first
+// two being the namespace and name of the ControlPlane, and returns them
+func trackLabels(vec labelDeleter, values ...string) []string {
+	key := types.NamespacedName{Namespace: values[0], Name: values[1]}
+	controlPlaneSeriesLock.Lock()
+	defer controlPlaneSeriesLock.Unlock()
+	if controlPlaneSeries[key] == nil {
+		controlPlaneSeries[key] = map[trackedSeries][]string{}
+	}
+	controlPlaneSeries[key][trackedSeries{vec: vec, labels: strings.Join(values, "\x00")}] = values
+	return values
+}
+
+// forgetControlPlaneMetrics removes all series recorded for a deleted ControlPlane
+func forgetControlPlaneMetrics(namespace string, name string) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	controlPlaneSeriesLock.Lock()
+	defer controlPlaneSeriesLock.Unlock()
+	for series, values := range controlPlaneSeries[key] {
+		series.vec.DeleteLabelValues(values...)
+	}
+	delete(controlPlaneSeries, key)
+}
+
+// recordApplyResult counts an applied object
+func recordApplyResult(instance *controlplanev1beta1.ControlPlane, result bindatautil.ApplyResult) {
+	gvk := result.Object.GroupVersionKind()
+	outcome := string(result.Operation)
+	if result.Err != nil {
+		outcome = "failed"
+	}
+	objectsApplied.WithLabelValues(trackLabels(objectsApplied, instance.Namespace, instance.Name, gvk.Group, gvk.Version, gvk.Kind, outcome)...).Inc()
+}
+
+// recordRolloutStatus updates the service gauges and observes the duration of
+// the rollout phases completed since the old status
+func recordRolloutStatus(instance *controlplanev1beta1.ControlPlane, oldStatus *controlplanev1beta1.ControlPlaneStatus) {
+	servicesDesired.WithLabelValues(trackLabels(servicesDesired, instance.Namespace, instance.Name)...).Set(float64(len(serviceManifestDirs)))
+
+	ready := 0
+	for i, phase := range rolloutPhases {
+		if i+1 < instance.Status.RolloutPhase || instance.Status.Ready {
+			ready += len(phase)
+		}
+	}
+	servicesReady.WithLabelValues(trackLabels(servicesReady, instance.Namespace, instance.Name)...).Set(float64(ready))
+
+	// the old phase completed if the rollout moved on or became ready
+	phaseCompleted := instance.Status.RolloutPhase > oldStatus.RolloutPhase ||
+		(instance.Status.Ready && !oldStatus.Ready && instance.Status.RolloutPhase == oldStatus.RolloutPhase)
+	if oldStatus.RolloutPhase > 0 && oldStatus.RolloutPhaseStartTime != nil && phaseCompleted {
+		rolloutPhaseDuration.WithLabelValues(trackLabels(rolloutPhaseDuration, instance.Namespace, instance.Name, strconv.Itoa(oldStatus.RolloutPhase))...).
+			Observe(time.Since(oldStatus.RolloutPhaseStartTime.Time).Seconds())
+	}
+}
+
+// recordSecretAges sets the age of the rendered secrets which exist already
+func recordSecretAges(ctx context.Context, c client.Client, instance *controlplanev1beta1.ControlPlane, objs []*uns.Unstructured) error {
+	for _, obj := range objs {
+		if obj.GetKind() != "Secret" {
+			continue
+		}
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, secret)
+		if err != nil {
+			if k8s_errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		changed := secret.CreationTimestamp.Time
+		if rotated, err := time.Parse(time.RFC3339, secret.GetAnnotations()[rotatedAtAnnotation]); err == nil {
+			changed = rotated
+		}
+		secretAge.WithLabelValues(trackLabels(secretAge, instance.Namespace, instance.Name, secret.Name)...).
+			Set(time.Since(changed).Seconds())
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	bindatautil "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/bindata_util"
+)
+
+// countSeries returns the number of series of the collector
+func countSeries(c prometheus.Collector) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+	n := 0
+	for range ch {
+		n++
+	}
+	return n
+}
+
+func TestForgetControlPlaneMetrics(t *testing.T) {
+	deleted := &controlplanev1beta1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "metrics-test"}}
+	kept := &controlplanev1beta1.ControlPlane{ObjectMeta: metav1.ObjectMeta{Name: "kept", Namespace: "metrics-test"}}
+	collectors := []prometheus.Collector{servicesDesired, servicesReady, objectsApplied, renderErrors, rolloutPhaseDuration, secretAge}
+	before := make([]int, len(collectors))
+	for i, c := range collectors {
+		before[i] = countSeries(c)
+	}
+
+	for _, instance := range []*controlplanev1beta1.ControlPlane{deleted, kept} {
+		// the rollout of phase 1 completed
+		instance.Status.RolloutPhase = 1
+		instance.Status.Ready = true
+		start := metav1.Now()
+		recordRolloutStatus(instance, &controlplanev1beta1.ControlPlaneStatus{RolloutPhase: 1, RolloutPhaseStartTime: &start})
+		obj := &uns.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("Secret")
+		recordApplyResult(instance, bindatautil.ApplyResult{Object: obj})
+		renderErrors.WithLabelValues(trackLabels(renderErrors, instance.Namespace, instance.Name)...).Inc()
+		secretAge.WithLabelValues(trackLabels(secretAge, instance.Namespace, instance.Name, "keystone-secret")...).Set(1)
+	}
+	forgetControlPlaneMetrics(deleted.Namespace, deleted.Name)
+
+	for i, c := range collectors {
+		// only the series of the kept ControlPlane are left
+		if n := countSeries(c) - before[i]; n != 1 {
+			t.Errorf("collector %d: %d series left, expected 1", i, n)
+		}
+	}
+	forgetControlPlaneMetrics(kept.Namespace, kept.Name)
+	for i, c := range collectors {
+		if n := countSeries(c) - before[i]; n != 0 {
+			t.Errorf("collector %d: %d series left, expected 0", i, n)
+		}
+	}
+}
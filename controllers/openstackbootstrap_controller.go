@@ -0,0 +1,382 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	util "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/util"
+)
+
+// OpenStackBootstrapReconciler reconciles a OpenStackBootstrap object
+type OpenStackBootstrapReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// bootstrapItem is a single resource converged by its own job
+type bootstrapItem struct {
+	kind   string
+	name   string
+	script []string
+}
+
+var invalidJobNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// +kubebuilder:rbac:groups=controlplane.openstack.org,resources=openstackbootstraps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=controlplane.openstack.org,resources=openstackbootstraps/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile OpenStackBootstrap requests
+func (r *OpenStackBootstrapReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	_ = context.Background()
+	log := r.Log.WithValues("openstackbootstrap", req.NamespacedName)
+
+	instance := &controlplanev1beta1.OpenStackBootstrap{}
+	err := r.Client.Get(context.TODO(), req.NamespacedName, instance)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	controlPlane := &controlplanev1beta1.ControlPlane{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: instance.Spec.ControlPlane, Namespace: instance.Namespace}, controlPlane)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			log.Info("ControlPlane not found, requeue", "ControlPlane", instance.Spec.ControlPlane)
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	// the commands need the services of the control plane
+	if !controlPlane.Status.Ready {
+		log.Info("ControlPlane not ready, requeue", "ControlPlane", instance.Spec.ControlPlane)
+		return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+	}
+
+	osClient := &controlplanev1beta1.OpenStackClient{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: instance.Spec.OpenStackClient, Namespace: instance.Namespace}, osClient)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			log.Info("OpenStackClient not found, requeue", "OpenStackClient", instance.Spec.OpenStackClient)
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	// networks can be owned by the projects, so projects have to exist first.
+	// Each group is only started once the previous one converged.
+	groups := [][]bootstrapItem{
+		getBootstrapProjects(instance.Spec.Projects),
+		getBootstrapNetworks(instance.Spec.Networks),
+		getBootstrapFlavors(instance.Spec.Flavors),
+		getBootstrapImages(instance.Spec.Images),
+	}
+
+	// retries are kept across reconciles in the item status
+	retries := map[string]int32{}
+	for _, itemStatus := range instance.Status.Items {
+		retries[itemStatus.Kind+"/"+itemStatus.Name] = itemStatus.Retries
+	}
+
+	items := []controlplanev1beta1.BootstrapItemStatus{}
+	ready := true
+	for _, group := range groups {
+		groupReady := true
+		for _, item := range group {
+			itemStatus := controlplanev1beta1.BootstrapItemStatus{
+				Kind:    item.kind,
+				Name:    item.name,
+				Phase:   controlplanev1beta1.CommandPhasePending,
+				Retries: retries[item.kind+"/"+item.name],
+			}
+			if ready {
+				itemStatus.Job = getBootstrapJobName(instance.Name, item)
+				err = r.reconcileItemJob(instance, osClient, item, &itemStatus)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+			}
+			if itemStatus.Phase != controlplanev1beta1.CommandPhaseSucceeded {
+				groupReady = false
+			}
+			items = append(items, itemStatus)
+		}
+		ready = ready && groupReady
+	}
+
+	oldStatus := instance.Status.DeepCopy()
+	instance.Status.Items = items
+	instance.Status.Ready = ready
+	if !reflect.DeepEqual(oldStatus, &instance.Status) {
+		if err := r.Client.Status().Update(context.TODO(), instance); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if !ready {
+		return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager func
+func (r *OpenStackBootstrapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&controlplanev1beta1.OpenStackBootstrap{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+// reconcileItemJob makes sure the job for the current definition of the item
+// exists and records its phase in the item status. A failed job gets
+// recreated with the same backoff as the OpenStackCommand jobs.
+func (r *OpenStackBootstrapReconciler) reconcileItemJob(instance *controlplanev1beta1.OpenStackBootstrap, osClient *controlplanev1beta1.OpenStackClient, item bootstrapItem, itemStatus *controlplanev1beta1.BootstrapItemStatus) error {
+	jobHash, err := util.CalculateHash(item.script)
+	if err != nil {
+		return err
+	}
+
+	job := &batchv1.Job{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: itemStatus.Job, Namespace: instance.Namespace}, job)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return err
+	}
+
+	if err == nil && job.Annotations[commandJobHashAnnotation] != jobHash {
+		// the definition changed, the job gets recreated on the next reconcile
+		r.Log.Info("Bootstrap item changed, deleting previous job", "Job", job.Name)
+		err = r.Client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return err
+		}
+		itemStatus.Phase = controlplanev1beta1.CommandPhasePending
+		itemStatus.Retries = 0
+		return nil
+	}
+
+	if k8s_errors.IsNotFound(err) {
+		job = getOpenStackClientJob(itemStatus.Job, instance.Namespace, osClient, item.script, jobHash)
+		backoffLimit := commandDefaultBackoffLimit
+		job.Spec.BackoffLimit = &backoffLimit
+		if err := controllerutil.SetControllerReference(instance, job, r.Scheme); err != nil {
+			return err
+		}
+		r.Log.Info("Creating bootstrap job", "Job", job.Name)
+		if err := r.Client.Create(context.TODO(), job); err != nil {
+			return err
+		}
+		itemStatus.Phase = controlplanev1beta1.CommandPhasePending
+		return nil
+	}
+
+	itemStatus.Phase, itemStatus.CompletionTime = getJobPhase(job)
+	if itemStatus.Phase == controlplanev1beta1.CommandPhaseFailed &&
+		getCommandRetryWait(itemStatus.CompletionTime, itemStatus.Retries, time.Now()) <= 0 {
+		// remove the failed job, the next reconcile creates a new one
+		r.Log.Info("Retrying failed bootstrap job", "Job", job.Name, "Retries", itemStatus.Retries+1)
+		err = r.Client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return err
+		}
+		itemStatus.Phase = controlplanev1beta1.CommandPhasePending
+		itemStatus.CompletionTime = nil
+		itemStatus.Retries++
+	}
+	return nil
+}
+
+// getBootstrapJobName returns a valid job name for the item. Names which
+// only differ in case or invalid characters would map to the same job, so a
+// short hash of the original names gets appended.
+func getBootstrapJobName(instanceName string, item bootstrapItem) string {
+	sum := fmt.Sprintf("%x", md5.Sum([]byte(instanceName+"/"+item.kind+"/"+item.name)))[:8]
+	name := strings.ToLower(fmt.Sprintf("%s-%s-%s", instanceName, item.kind, item.name))
+	name = strings.Trim(invalidJobNameChars.ReplaceAllString(name, "-"), "-")
+	// job names end up in the job-name pod label, which is limited to 63 chars
+	if len(name) > 63-len(sum)-1 {
+		name = strings.TrimRight(name[:63-len(sum)-1], "-")
+	}
+	return name + "-" + sum
+}
+
+func getBootstrapProjects(projects []controlplanev1beta1.BootstrapProject) []bootstrapItem {
+	items := []bootstrapItem{}
+	for _, p := range projects {
+		domain := p.Domain
+		if domain == "" {
+			domain = "Default"
+		}
+		create := []string{"openstack project create", "--domain", shellQuote(domain)}
+		if p.Description != "" {
+			create = append(create, "--description", shellQuote(p.Description))
+		}
+		create = append(create, shellQuote(p.Name))
+
+		script := []string{
+			fmt.Sprintf("openstack project show --domain %s %s >/dev/null 2>&1 || %s", shellQuote(domain), shellQuote(p.Name), strings.Join(create, " ")),
+		}
+		if len(p.Quotas) > 0 {
+			quota := []string{"openstack quota set"}
+			keys := []string{}
+			for k := range p.Quotas {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				quota = append(quota, fmt.Sprintf("%s %d", shellQuote("--"+k), p.Quotas[k]))
+			}
+			// quota set has no domain option, the name is looked up in the
+			// domain of the token otherwise
+			quota = append(quota, fmt.Sprintf(`"$(openstack project show --domain %s -f value -c id %s)"`, shellQuote(domain), shellQuote(p.Name)))
+			script = append(script, strings.Join(quota, " "))
+		}
+		// project names are only unique within their domain
+		items = append(items, bootstrapItem{kind: "Project", name: domain + "/" + p.Name, script: script})
+	}
+	return items
+}
+
+func getBootstrapNetworks(networks []controlplanev1beta1.BootstrapNetwork) []bootstrapItem {
+	items := []bootstrapItem{}
+	for _, n := range networks {
+		create := []string{"openstack network create"}
+		if n.Project != "" {
+			create = append(create, "--project", shellQuote(n.Project))
+		}
+		if n.External {
+			create = append(create, "--external")
+		}
+		if n.Shared {
+			create = append(create, "--share")
+		}
+		if n.ProviderNetworkType != "" {
+			create = append(create, "--provider-network-type", shellQuote(n.ProviderNetworkType))
+		}
+		if n.ProviderPhysicalNetwork != "" {
+			create = append(create, "--provider-physical-network", shellQuote(n.ProviderPhysicalNetwork))
+		}
+		if n.ProviderSegment != nil {
+			create = append(create, fmt.Sprintf("--provider-segment %d", *n.ProviderSegment))
+		}
+		create = append(create, shellQuote(n.Name))
+
+		script := []string{
+			fmt.Sprintf("openstack network show %s >/dev/null 2>&1 || %s", shellQuote(n.Name), strings.Join(create, " ")),
+		}
+		for _, s := range n.Subnets {
+			subnet := []string{"openstack subnet create", "--network", shellQuote(n.Name), "--subnet-range", shellQuote(s.CIDR)}
+			if s.Gateway != "" {
+				subnet = append(subnet, "--gateway", shellQuote(s.Gateway))
+			}
+			if s.AllocationPoolStart != "" && s.AllocationPoolEnd != "" {
+				subnet = append(subnet, "--allocation-pool", shellQuote(fmt.Sprintf("start=%s,end=%s", s.AllocationPoolStart, s.AllocationPoolEnd)))
+			}
+			if s.DisableDHCP {
+				subnet = append(subnet, "--no-dhcp")
+			}
+			subnet = append(subnet, shellQuote(s.Name))
+			script = append(script, fmt.Sprintf("openstack subnet show %s >/dev/null 2>&1 || %s", shellQuote(s.Name), strings.Join(subnet, " ")))
+		}
+		items = append(items, bootstrapItem{kind: "Network", name: n.Name, script: script})
+	}
+	return items
+}
+
+func getBootstrapFlavors(flavors []controlplanev1beta1.BootstrapFlavor) []bootstrapItem {
+	items := []bootstrapItem{}
+	for _, f := range flavors {
+		create := []string{
+			"openstack flavor create",
+			fmt.Sprintf("--vcpus %d --ram %d --disk %d", f.VCPUs, f.RAM, f.Disk),
+		}
+		if f.Private {
+			create = append(create, "--private")
+		} else {
+			create = append(create, "--public")
+		}
+		create = append(create, shellQuote(f.Name))
+
+		script := []string{
+			fmt.Sprintf("openstack flavor show %s >/dev/null 2>&1 || %s", shellQuote(f.Name), strings.Join(create, " ")),
+		}
+		if len(f.Properties) > 0 {
+			set := []string{"openstack flavor set"}
+			keys := []string{}
+			for k := range f.Properties {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				set = append(set, "--property", shellQuote(k+"="+f.Properties[k]))
+			}
+			set = append(set, shellQuote(f.Name))
+			script = append(script, strings.Join(set, " "))
+		}
+		items = append(items, bootstrapItem{kind: "Flavor", name: f.Name, script: script})
+	}
+	return items
+}
+
+func getBootstrapImages(images []controlplanev1beta1.BootstrapImage) []bootstrapItem {
+	items := []bootstrapItem{}
+	for _, i := range images {
+		diskFormat := i.DiskFormat
+		if diskFormat == "" {
+			diskFormat = "qcow2"
+		}
+		containerFormat := i.ContainerFormat
+		if containerFormat == "" {
+			containerFormat = "bare"
+		}
+		visibility := "--private"
+		if i.Public {
+			visibility = "--public"
+		}
+
+		script := []string{
+			fmt.Sprintf("if ! openstack image show %s >/dev/null 2>&1; then", shellQuote(i.Name)),
+			fmt.Sprintf("curl -sSfL -o /tmp/image %s", shellQuote(i.URL)),
+			fmt.Sprintf("openstack image create --disk-format %s --container-format %s %s --file /tmp/image %s",
+				shellQuote(diskFormat), shellQuote(containerFormat), visibility, shellQuote(i.Name)),
+			"rm -f /tmp/image",
+			"fi",
+		}
+		items = append(items, bootstrapItem{kind: "Image", name: i.Name, script: script})
+	}
+	return items
+}
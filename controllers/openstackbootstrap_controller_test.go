@@ -0,0 +1,82 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+)
+
+func TestGetBootstrapJobName(t *testing.T) {
+	validName := regexp.MustCompile("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$")
+	tests := []struct {
+		instance string
+		kind     string
+		names    []string
+	}{
+		{instance: "bootstrap", kind: "Flavor", names: []string{"m1.tiny", "m1-tiny", "M1.tiny"}},
+		{instance: "bootstrap", kind: "Network", names: []string{
+			"public-" + strings.Repeat("x", 60) + "-a",
+			"public-" + strings.Repeat("x", 60) + "-b",
+		}},
+	}
+	for _, tt := range tests {
+		seen := map[string]string{}
+		for _, name := range tt.names {
+			jobName := getBootstrapJobName(tt.instance, bootstrapItem{kind: tt.kind, name: name})
+			if len(jobName) > 63 || !validName.MatchString(jobName) {
+				t.Errorf("%s %s: invalid job name %q", tt.kind, name, jobName)
+			}
+			if other, ok := seen[jobName]; ok {
+				t.Errorf("%s %s: job name %q collides with %s", tt.kind, name, jobName, other)
+			}
+			seen[jobName] = name
+		}
+	}
+}
+
+func TestGetBootstrapProjectsQuotesQuotaKeys(t *testing.T) {
+	items := getBootstrapProjects([]controlplanev1beta1.BootstrapProject{
+		{Name: "demo", Quotas: map[string]int{"cores": 20, "x; rm -rf /": 1}},
+	})
+	quota := items[0].script[1]
+	expected := `openstack quota set '--cores' 20 '--x; rm -rf /' 1 "$(openstack project show --domain 'Default' -f value -c id 'demo')"`
+	if quota != expected {
+		t.Errorf("quota command %q, expected %q", quota, expected)
+	}
+}
+
+func TestGetBootstrapProjectsInDomains(t *testing.T) {
+	items := getBootstrapProjects([]controlplanev1beta1.BootstrapProject{
+		{Name: "demo", Quotas: map[string]int{"cores": 20}},
+		{Name: "demo", Domain: "tenants", Quotas: map[string]int{"cores": 10}},
+	})
+	if items[0].name == items[1].name {
+		t.Fatalf("projects in different domains share the item name %q", items[0].name)
+	}
+	if a, b := getBootstrapJobName("bootstrap", items[0]), getBootstrapJobName("bootstrap", items[1]); a == b {
+		t.Errorf("projects in different domains share the job %q", a)
+	}
+	quota := items[1].script[1]
+	expected := `openstack quota set '--cores' 10 "$(openstack project show --domain 'tenants' -f value -c id 'demo')"`
+	if quota != expected {
+		t.Errorf("quota command %q, expected %q", quota, expected)
+	}
+}
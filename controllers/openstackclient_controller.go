@@ -26,6 +26,7 @@ import (
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -36,12 +37,14 @@ import (
 // OpenStackClientReconciler reconciles a OpenStackClient object
 type OpenStackClientReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=controlplane.openstack.org,resources=openstackclients,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=controlplane.openstack.org,resources=openstackclients/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile OpenStackClient requests
 func (r *OpenStackClientReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -81,27 +84,8 @@ func (r *OpenStackClientReconciler) reconcileDeployment(instance *controlplanev1
 	}
 
 	r.Log.Info("openstack-config-secret name", "Name", instance.Spec.OpenStackConfigSecret)
-	_, err := controllerutil.CreateOrUpdate(context.TODO(), r.Client, clientDeployment, func() error {
-		clientDeployment.Spec.Template.Spec.Volumes = []corev1.Volume{
-			{
-				Name: "openstack-config",
-				VolumeSource: corev1.VolumeSource{
-					ConfigMap: &corev1.ConfigMapVolumeSource{
-						LocalObjectReference: corev1.LocalObjectReference{
-							Name: instance.Spec.OpenStackConfigMap,
-						},
-					},
-				},
-			},
-			{
-				Name: "openstack-config-secret",
-				VolumeSource: corev1.VolumeSource{
-					Secret: &corev1.SecretVolumeSource{
-						SecretName: instance.Spec.OpenStackConfigSecret,
-					},
-				},
-			},
-		}
+	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.Client, clientDeployment, func() error {
+		clientDeployment.Spec.Template.Spec.Volumes = getOpenStackClientVolumes(instance)
 
 		labels := map[string]string{
 			"app": "openstackclient",
@@ -118,32 +102,76 @@ func (r *OpenStackClientReconciler) reconcileDeployment(instance *controlplanev1
 		}
 		clientDeployment.Spec.Template.Spec.Containers = []corev1.Container{
 			{
-				Name:    "openstackclient",
-				Image:   instance.Spec.ContainerImage,
-				Command: []string{"sleep", "infinity"},
-				Env: []corev1.EnvVar{
-					{
-						Name:  "OS_CLOUD",
-						Value: "default",
-					},
-				},
-				VolumeMounts: []corev1.VolumeMount{
-					{
-						Name:      "openstack-config",
-						MountPath: "/etc/openstack/clouds.yaml",
-						SubPath:   "clouds.yaml",
-					},
-					{
-						Name:      "openstack-config-secret",
-						MountPath: "/etc/openstack/secure.yaml",
-						SubPath:   "secure.yaml",
-					},
-				},
+				Name:         "openstackclient",
+				Image:        instance.Spec.ContainerImage,
+				Command:      []string{"sleep", "infinity"},
+				Env:          getOpenStackClientEnv(),
+				VolumeMounts: getOpenStackClientVolumeMounts(),
 			},
 		}
 
 		return nil
 	})
+	if err != nil {
+		r.Recorder.Eventf(instance, corev1.EventTypeWarning, eventReasonApplyFailed, "Failed to apply Deployment %s: %v", clientDeployment.Name, err)
+		return err
+	}
+
+	switch op {
+	case controllerutil.OperationResultCreated:
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonCreated, "Created Deployment %s", clientDeployment.Name)
+	case controllerutil.OperationResultUpdated:
+		r.Recorder.Eventf(instance, corev1.EventTypeNormal, eventReasonUpdated, "Updated Deployment %s", clientDeployment.Name)
+	}
+	return nil
+}
+
+// getOpenStackClientVolumes returns the cloud config volumes of an OpenStackClient
+func getOpenStackClientVolumes(instance *controlplanev1beta1.OpenStackClient) []corev1.Volume {
+	return []corev1.Volume{
+		{
+			Name: "openstack-config",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: instance.Spec.OpenStackConfigMap,
+					},
+				},
+			},
+		},
+		{
+			Name: "openstack-config-secret",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: instance.Spec.OpenStackConfigSecret,
+				},
+			},
+		},
+	}
+}
 
-	return err
+// getOpenStackClientVolumeMounts returns where the cloud config volumes get mounted
+func getOpenStackClientVolumeMounts() []corev1.VolumeMount {
+	return []corev1.VolumeMount{
+		{
+			Name:      "openstack-config",
+			MountPath: "/etc/openstack/clouds.yaml",
+			SubPath:   "clouds.yaml",
+		},
+		{
+			Name:      "openstack-config-secret",
+			MountPath: "/etc/openstack/secure.yaml",
+			SubPath:   "secure.yaml",
+		},
+	}
+}
+
+// getOpenStackClientEnv returns the env to select the cloud from clouds.yaml
+func getOpenStackClientEnv() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  "OS_CLOUD",
+			Value: "default",
+		},
+	}
 }
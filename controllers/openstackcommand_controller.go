@@ -0,0 +1,435 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	util "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/util"
+)
+
+const (
+	// maximum length of the command output stored in the status
+	commandOutputMaxLength = 1024
+	// default number of retries of a command job
+	commandDefaultBackoffLimit int32 = 3
+	// default number of times the job of a failed command gets recreated
+	commandDefaultMaxRetries int32 = 3
+	// annotation on the job with the hash of the spec it was created for
+	commandJobHashAnnotation = "controlplane.openstack.org/job-hash"
+	// wait before the job of a failed command gets recreated, doubled with
+	// every retry up to commandRetryMaxBackoff
+	commandRetryMinBackoff = time.Minute
+	commandRetryMaxBackoff = time.Hour
+)
+
+// OpenStackCommandReconciler reconciles a OpenStackCommand object
+type OpenStackCommandReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=controlplane.openstack.org,resources=openstackcommands,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=controlplane.openstack.org,resources=openstackcommands/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Reconcile OpenStackCommand requests
+func (r *OpenStackCommandReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	_ = context.Background()
+	log := r.Log.WithValues("openstackcommand", req.NamespacedName)
+
+	instance := &controlplanev1beta1.OpenStackCommand{}
+	err := r.Client.Get(context.TODO(), req.NamespacedName, instance)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	jobHash, err := util.CalculateHash(instance.Spec)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	script, err := getCommandScript(instance.Spec.Commands)
+	if err != nil {
+		// retrying won't help before the commands get fixed
+		log.Info("Invalid command", "Error", err.Error())
+		oldStatus := instance.Status.DeepCopy()
+		instance.Status = controlplanev1beta1.OpenStackCommandStatus{
+			JobHash: jobHash,
+			Phase:   controlplanev1beta1.CommandPhaseFailed,
+			Output:  err.Error(),
+		}
+		return ctrl.Result{}, r.updateStatus(instance, oldStatus)
+	}
+
+	// the job of a finished command may already be gone because of the TTL,
+	// only start a new one when the spec changed or a failed command is due
+	// for a retry
+	retry := false
+	if instance.Status.JobHash == jobHash {
+		switch instance.Status.Phase {
+		case controlplanev1beta1.CommandPhaseSucceeded:
+			return ctrl.Result{}, nil
+		case controlplanev1beta1.CommandPhaseFailed:
+			// the command stays failed until the spec changes
+			if instance.Status.Retries >= getCommandMaxRetries(&instance.Spec) {
+				log.Info("Command failed, no retries left", "Retries", instance.Status.Retries)
+				return ctrl.Result{}, nil
+			}
+			if wait := getCommandRetryWait(instance.Status.CompletionTime, instance.Status.Retries, time.Now()); wait > 0 {
+				return ctrl.Result{RequeueAfter: wait}, nil
+			}
+			retry = true
+		}
+	}
+
+	osClient := &controlplanev1beta1.OpenStackClient{}
+	err = r.Client.Get(context.TODO(), types.NamespacedName{Name: instance.Spec.OpenStackClient, Namespace: instance.Namespace}, osClient)
+	if err != nil {
+		if k8s_errors.IsNotFound(err) {
+			log.Info("OpenStackClient not found, requeue", "OpenStackClient", instance.Spec.OpenStackClient)
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	job := &batchv1.Job{}
+	err = r.Client.Get(context.TODO(), req.NamespacedName, job)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	if retry {
+		// remove the failed job, the next reconcile creates a new one
+		log.Info("Retrying failed command", "Retries", instance.Status.Retries+1)
+		if err == nil {
+			err = r.Client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !k8s_errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+		}
+		oldStatus := instance.Status.DeepCopy()
+		instance.Status.Retries++
+		instance.Status.Phase = controlplanev1beta1.CommandPhasePending
+		instance.Status.CompletionTime = nil
+		if err := r.updateStatus(instance, oldStatus); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+	}
+
+	if err == nil && job.Annotations[commandJobHashAnnotation] != jobHash {
+		// the commands changed, remove the job of the previous spec
+		log.Info("Commands changed, deleting previous job", "Job", job.Name)
+		err = r.Client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: time.Second * 5}, nil
+	}
+
+	if k8s_errors.IsNotFound(err) {
+		job = getCommandJob(instance, osClient, script, jobHash)
+		if err := controllerutil.SetControllerReference(instance, job, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		log.Info("Creating command job", "Job", job.Name)
+		if err := r.Client.Create(context.TODO(), job); err != nil {
+			return ctrl.Result{}, err
+		}
+		oldStatus := instance.Status.DeepCopy()
+		// retries only count for the same spec
+		retries := int32(0)
+		if instance.Status.JobHash == jobHash {
+			retries = instance.Status.Retries
+		}
+		instance.Status = controlplanev1beta1.OpenStackCommandStatus{
+			JobHash: jobHash,
+			Phase:   controlplanev1beta1.CommandPhasePending,
+			Retries: retries,
+		}
+		return ctrl.Result{}, r.updateStatus(instance, oldStatus)
+	}
+
+	if err := r.updateStatusFromJob(instance, job); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager func
+func (r *OpenStackCommandReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&controlplanev1beta1.OpenStackCommand{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+// updateStatusFromJob records the job state and the result of its last pod
+func (r *OpenStackCommandReconciler) updateStatusFromJob(instance *controlplanev1beta1.OpenStackCommand, job *batchv1.Job) error {
+	oldStatus := instance.Status.DeepCopy()
+	status := &instance.Status
+	status.JobHash = job.Annotations[commandJobHashAnnotation]
+	status.Attempts = job.Status.Succeeded + job.Status.Failed
+	status.Phase, status.CompletionTime = getJobPhase(job)
+
+	pods := &corev1.PodList{}
+	err := r.Client.List(context.TODO(), pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name})
+	if err != nil {
+		return err
+	}
+	var last *corev1.ContainerStateTerminated
+	for _, pod := range pods.Items {
+		for _, cs := range pod.Status.ContainerStatuses {
+			t := cs.State.Terminated
+			if t == nil {
+				continue
+			}
+			if last == nil || last.FinishedAt.Before(&t.FinishedAt) {
+				last = t
+			}
+		}
+	}
+	if last != nil {
+		exitCode := last.ExitCode
+		status.ExitCode = &exitCode
+		status.Output = truncateOutput(last.Message, commandOutputMaxLength)
+	}
+
+	return r.updateStatus(instance, oldStatus)
+}
+
+// updateStatus writes the status if it differs from oldStatus
+func (r *OpenStackCommandReconciler) updateStatus(instance *controlplanev1beta1.OpenStackCommand, oldStatus *controlplanev1beta1.OpenStackCommandStatus) error {
+	if reflect.DeepEqual(oldStatus, &instance.Status) {
+		return nil
+	}
+	return r.Client.Status().Update(context.TODO(), instance)
+}
+
+// getCommandMaxRetries returns how often the job of a failed command gets
+// recreated
+func getCommandMaxRetries(spec *controlplanev1beta1.OpenStackCommandSpec) int32 {
+	if spec.MaxRetries == nil {
+		return commandDefaultMaxRetries
+	}
+	return *spec.MaxRetries
+}
+
+// getCommandRetryWait returns how long a job which failed at completed has to
+// wait before it gets recreated for the next retry
+func getCommandRetryWait(completed *metav1.Time, retries int32, now time.Time) time.Duration {
+	if completed == nil {
+		return 0
+	}
+	backoff := commandRetryMinBackoff
+	for i := int32(0); i < retries && backoff < commandRetryMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > commandRetryMaxBackoff {
+		backoff = commandRetryMaxBackoff
+	}
+	return completed.Add(backoff).Sub(now)
+}
+
+// getCommandScript returns the script lines running the commands. The
+// commands get split into their arguments which are quoted again, so they
+// can't run anything but the openstack CLI.
+func getCommandScript(commands []string) ([]string, error) {
+	script := []string{}
+	for _, cmd := range commands {
+		args, err := splitCommand(cmd)
+		if err != nil {
+			return nil, err
+		}
+		line := "openstack"
+		for _, arg := range args {
+			line += " " + shellQuote(arg)
+		}
+		script = append(script, fmt.Sprintf("echo %s", shellQuote("+ "+line)))
+		script = append(script, line)
+	}
+	return script, nil
+}
+
+// splitCommand splits a command into its arguments like the shell does for
+// words, single and double quotes and backslash escapes. Nothing gets
+// expanded.
+func splitCommand(cmd string) ([]string, error) {
+	args := []string{}
+	var arg strings.Builder
+	inArg := false
+	escaped := false
+	var quote rune
+	for _, c := range cmd {
+		switch {
+		case escaped:
+			// within double quotes the backslash only escapes itself and the quote
+			if quote == '"' && c != '"' && c != '\\' {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(c)
+			escaped = false
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == '\'':
+			arg.WriteRune(c)
+		case c == '\\':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			arg.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case unicode.IsSpace(c):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in command %q", cmd)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in command %q", cmd)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
+
+// getCommandJob returns a job running the command script with the image and
+// cloud config of the OpenStackClient
+func getCommandJob(instance *controlplanev1beta1.OpenStackCommand, osClient *controlplanev1beta1.OpenStackClient, script []string, jobHash string) *batchv1.Job {
+	backoffLimit := commandDefaultBackoffLimit
+	if instance.Spec.BackoffLimit != nil {
+		backoffLimit = *instance.Spec.BackoffLimit
+	}
+
+	job := getOpenStackClientJob(instance.Name, instance.Namespace, osClient, script, jobHash)
+	job.Spec.BackoffLimit = &backoffLimit
+	job.Spec.TTLSecondsAfterFinished = instance.Spec.TTLSecondsAfterFinished
+	return job
+}
+
+// getOpenStackClientJob returns a job running the script with the image and
+// cloud config of the OpenStackClient. The script stops at the first failure
+// and its output is copied into the termination log so it ends up in the pod status.
+func getOpenStackClientJob(name string, namespace string, osClient *controlplanev1beta1.OpenStackClient, script []string, jobHash string) *batchv1.Job {
+	lines := []string{"set -o pipefail", "{", "set -e"}
+	lines = append(lines, script...)
+	lines = append(lines, "} 2>&1 | tee "+corev1.TerminationMessagePathDefault)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Annotations: map[string]string{
+				commandJobHashAnnotation: jobHash,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": "openstackcommand",
+					},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Volumes:       getOpenStackClientVolumes(osClient),
+					Containers: []corev1.Container{
+						{
+							Name:                     "openstackcommand",
+							Image:                    osClient.Spec.ContainerImage,
+							Command:                  []string{"/bin/bash", "-c", strings.Join(lines, "\n")},
+							Env:                      getOpenStackClientEnv(),
+							VolumeMounts:             getOpenStackClientVolumeMounts(),
+							TerminationMessagePath:   corev1.TerminationMessagePathDefault,
+							TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// getJobPhase maps the job state to a command phase
+func getJobPhase(job *batchv1.Job) (string, *metav1.Time) {
+	phase := controlplanev1beta1.CommandPhasePending
+	if job.Status.Active > 0 {
+		phase = controlplanev1beta1.CommandPhaseRunning
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return controlplanev1beta1.CommandPhaseSucceeded, job.Status.CompletionTime
+		case batchv1.JobFailed:
+			return controlplanev1beta1.CommandPhaseFailed, c.LastTransitionTime.DeepCopy()
+		}
+	}
+	return phase, nil
+}
+
+// shellQuote single quotes s for use in a bash script
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// truncateOutput keeps the tail of the output, which holds the error if a command failed
+func truncateOutput(output string, max int) string {
+	if len(output) <= max {
+		return output
+	}
+	return output[len(output)-max:]
+}
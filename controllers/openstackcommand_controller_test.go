@@ -0,0 +1,180 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	controlplanev1beta1 "github.com/openstack-k8s-operators/openstack-cluster-operator/api/v1beta1"
+	util "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/util"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		cmd     string
+		args    []string
+		invalid bool
+	}{
+		{cmd: "flavor list", args: []string{"flavor", "list"}},
+		{cmd: "  project   create  demo ", args: []string{"project", "create", "demo"}},
+		{cmd: `project create --description 'a demo project' demo`, args: []string{"project", "create", "--description", "a demo project", "demo"}},
+		{cmd: `image create --property "os_distro=cirros os" img`, args: []string{"image", "create", "--property", "os_distro=cirros os", "img"}},
+		{cmd: `network create net\ one`, args: []string{"network", "create", "net one"}},
+		{cmd: `network create "a\"b" "c\d"`, args: []string{"network", "create", `a"b`, `c\d`}},
+		{cmd: `server list; rm -rf /`, args: []string{"server", "list;", "rm", "-rf", "/"}},
+		{cmd: `flavor list $(id) ''`, args: []string{"flavor", "list", "$(id)", ""}},
+		{cmd: `project create 'demo`, invalid: true},
+		{cmd: `project create demo\`, invalid: true},
+		{cmd: "   ", invalid: true},
+	}
+	for _, tt := range tests {
+		args, err := splitCommand(tt.cmd)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("splitCommand(%q): expected an error, got %q", tt.cmd, args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("splitCommand(%q): %v", tt.cmd, err)
+			continue
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("splitCommand(%q) = %q, expected %q", tt.cmd, args, tt.args)
+		}
+	}
+}
+
+func TestGetCommandScript(t *testing.T) {
+	script, err := getCommandScript([]string{`project create --description "it's demo" demo`})
+	if err != nil {
+		t.Fatalf("getCommandScript: %v", err)
+	}
+	expected := []string{
+		`echo '+ openstack '\''project'\'' '\''create'\'' '\''--description'\'' '\''it'\''\'\'''\''s demo'\'' '\''demo'\'''`,
+		`openstack 'project' 'create' '--description' 'it'\''s demo' 'demo'`,
+	}
+	if !reflect.DeepEqual(script, expected) {
+		t.Errorf("getCommandScript = %q, expected %q", script, expected)
+	}
+}
+
+func TestGetCommandRetryWait(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		completed *metav1.Time
+		retries   int32
+		wait      time.Duration
+	}{
+		{name: "no completion time", wait: 0},
+		{name: "first retry", completed: &metav1.Time{Time: now.Add(-10 * time.Second)}, wait: 50 * time.Second},
+		{name: "doubled", completed: &metav1.Time{Time: now}, retries: 2, wait: 4 * time.Minute},
+		{name: "capped", completed: &metav1.Time{Time: now}, retries: 20, wait: time.Hour},
+		{name: "due", completed: &metav1.Time{Time: now.Add(-2 * time.Hour)}, retries: 3, wait: -2*time.Hour + 8*time.Minute},
+	}
+	for _, tt := range tests {
+		if wait := getCommandRetryWait(tt.completed, tt.retries, now); wait != tt.wait {
+			t.Errorf("%s: wait %v, expected %v", tt.name, wait, tt.wait)
+		}
+	}
+}
+
+func TestReconcileCommandMaxRetries(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = scheme.AddToScheme(s)
+	_ = controlplanev1beta1.AddToScheme(s)
+
+	maxRetries := int32(1)
+	instance := &controlplanev1beta1.OpenStackCommand{
+		ObjectMeta: metav1.ObjectMeta{Name: "flavors", Namespace: "openstack"},
+		Spec: controlplanev1beta1.OpenStackCommandSpec{
+			OpenStackClient: "openstackclient",
+			Commands:        []string{"flavor list"},
+			MaxRetries:      &maxRetries,
+		},
+	}
+	jobHash, err := util.CalculateHash(instance.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	completed := metav1.NewTime(time.Now().Add(-2 * commandRetryMaxBackoff))
+	instance.Status = controlplanev1beta1.OpenStackCommandStatus{
+		JobHash:        jobHash,
+		Phase:          controlplanev1beta1.CommandPhaseFailed,
+		CompletionTime: &completed,
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:        instance.Name,
+		Namespace:   instance.Namespace,
+		Annotations: map[string]string{commandJobHashAnnotation: jobHash},
+	}}
+	osClient := &controlplanev1beta1.OpenStackClient{ObjectMeta: metav1.ObjectMeta{Name: "openstackclient", Namespace: "openstack"}}
+	c := fake.NewFakeClientWithScheme(s, instance, job, osClient)
+	r := &OpenStackCommandReconciler{Client: c, Log: logf.NullLogger{}, Scheme: s}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}}
+
+	// the first failure gets retried
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(context.TODO(), req.NamespacedName, instance); err != nil {
+		t.Fatal(err)
+	}
+	if instance.Status.Retries != 1 || instance.Status.Phase != controlplanev1beta1.CommandPhasePending {
+		t.Fatalf("expected a pending retry, got %+v", instance.Status)
+	}
+	if err := c.Get(context.TODO(), req.NamespacedName, &batchv1.Job{}); !k8s_errors.IsNotFound(err) {
+		t.Fatalf("expected the failed job to be deleted, got %v", err)
+	}
+
+	// the retry failed as well
+	instance.Status.Phase = controlplanev1beta1.CommandPhaseFailed
+	instance.Status.CompletionTime = &completed
+	if err := c.Status().Update(context.TODO(), instance); err != nil {
+		t.Fatal(err)
+	}
+	result, err := r.Reconcile(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (ctrl.Result{}) {
+		t.Errorf("expected no requeue once the retries are used up, got %+v", result)
+	}
+	if err := c.Get(context.TODO(), req.NamespacedName, instance); err != nil {
+		t.Fatal(err)
+	}
+	if instance.Status.Retries != 1 || instance.Status.Phase != controlplanev1beta1.CommandPhaseFailed {
+		t.Errorf("expected the command to stay failed, got %+v", instance.Status)
+	}
+	if err := c.Get(context.TODO(), req.NamespacedName, &batchv1.Job{}); !k8s_errors.IsNotFound(err) {
+		t.Errorf("expected no new job, got %v", err)
+	}
+}
@@ -1,11 +1,12 @@
 module github.com/openstack-k8s-operators/openstack-cluster-operator
 
-go 1.13
+go 1.16
 
 require (
 	github.com/Masterminds/semver v1.5.0 // indirect
 	github.com/Masterminds/sprig v2.22.0+incompatible
 	github.com/blang/semver v3.5.1+incompatible
+	github.com/evanphx/json-patch v4.5.0+incompatible
 	github.com/ghodss/yaml v1.0.0
 	github.com/go-logr/logr v0.1.0
 	github.com/imdario/mergo v0.3.9
@@ -16,6 +17,8 @@ require (
 	github.com/openstack-k8s-operators/neutron-operator v0.0.0-20201007084323-fd2c6dd27f5c // indirect
 	github.com/operator-framework/operator-lifecycle-manager v0.0.0-20200321030439-57b580e57e88
 	github.com/pkg/errors v0.9.1
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/prometheus/client_golang v1.2.1
 	k8s.io/api v0.18.6
 	k8s.io/apiextensions-apiserver v0.18.6
 	k8s.io/apimachinery v0.18.6
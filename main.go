@@ -47,14 +47,23 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var bindataDir string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&bindataDir, "bindata-dir", "",
+		"Render the manifests from this directory instead of the ones embedded into the binary. "+
+			"Intended for template development.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	if bindataDir != "" {
+		setupLog.Info("Using manifests from filesystem", "bindata-dir", bindataDir)
+		controllers.Manifests = os.DirFS(bindataDir)
+	}
+
 	namespace, found := os.LookupEnv("WATCH_NAMESPACE")
 	if !found {
 		setupLog.Info("Failed to get watch namespace")
@@ -75,19 +84,54 @@ func main() {
 	}
 
 	if err = (&controllers.ControlPlaneReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("ControlPlane"),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
+		Log:       ctrl.Log.WithName("controllers").WithName("ControlPlane"),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("controlplane-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ControlPlane")
 		os.Exit(1)
 	}
 	if err = (&controllers.OpenStackClientReconciler{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("controllers").WithName("OpenStackClient"),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("openstackclient-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "OpenStackClient")
+		os.Exit(1)
+	}
+	if err = (&controllers.OpenStackCommandReconciler{
 		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("OpenStackClient"),
+		Log:    ctrl.Log.WithName("controllers").WithName("OpenStackCommand"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "OpenStackClient")
+		setupLog.Error(err, "unable to create controller", "controller", "OpenStackCommand")
+		os.Exit(1)
+	}
+	if err = (&controllers.OpenStackBootstrapReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("OpenStackBootstrap"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "OpenStackBootstrap")
+		os.Exit(1)
+	}
+	if err = (&controllers.ControlPlaneBackupReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("ControlPlaneBackup"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ControlPlaneBackup")
+		os.Exit(1)
+	}
+	if err = (&controllers.ControlPlaneRestoreReconciler{
+		Client: mgr.GetClient(),
+		Log:    ctrl.Log.WithName("controllers").WithName("ControlPlaneRestore"),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ControlPlaneRestore")
 		os.Exit(1)
 	}
 	// +kubebuilder:scaffold:builder
@@ -3,60 +3,121 @@ package bindatautil
 import (
 	"context"
 	"fmt"
-	"log"
+	"sync"
 
+	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 
-	"k8s.io/apimachinery/pkg/api/equality"
+	util "github.com/openstack-k8s-operators/openstack-cluster-operator/pkg/util"
+
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+// HashAnnotation - annotation with the hash of the object as last applied
+const HashAnnotation = "controlplane.openstack.org/applied-hash"
+
+// ApplyResult - outcome of applying an object
+type ApplyResult struct {
+	Object *uns.Unstructured
+	// created, updated or unchanged
+	Operation controllerutil.OperationResult
+	Err       error
+}
+
+// ApplyObjects applies the objects concurrently, at most parallelism at a
+// time. A failing object doesn't stop the others, the results are returned in
+// the order of objs.
+func ApplyObjects(ctx context.Context, client k8sclient.Client, log logr.Logger, objs []*uns.Unstructured, parallelism int) []ApplyResult {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	results := make([]ApplyResult, len(objs))
+	sem := make(chan struct{}, parallelism)
+	wg := sync.WaitGroup{}
+
+	for i, obj := range objs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, obj *uns.Unstructured) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			op, err := ApplyObject(ctx, client, log, obj)
+			results[i] = ApplyResult{Object: obj, Operation: op, Err: err}
+		}(i, obj)
+	}
+	wg.Wait()
+
+	return results
+}
+
 // ApplyObject applies the desired object against the apiserver,
 // merging it with any existing objects if already present.
-func ApplyObject(ctx context.Context, client k8sclient.Client, obj *uns.Unstructured) error {
+func ApplyObject(ctx context.Context, client k8sclient.Client, log logr.Logger, obj *uns.Unstructured) (controllerutil.OperationResult, error) {
 	name := obj.GetName()
 	namespace := obj.GetNamespace()
 	if name == "" {
-		return errors.Errorf("Object %s has no name", obj.GroupVersionKind().String())
+		return controllerutil.OperationResultNone, errors.Errorf("Object %s has no name", obj.GroupVersionKind().String())
 	}
 	gvk := obj.GroupVersionKind()
-	// used for logging and errors
+	// used for errors
 	objDesc := fmt.Sprintf("(%s) %s/%s", gvk.String(), namespace, name)
-	log.Printf("reconciling %s", objDesc)
+	log = log.WithValues("Kind", gvk.Kind, "Namespace", namespace, "Name", name)
+	log.V(1).Info("Reconciling object")
+
+	// Record the hash of the desired object, existing objects with the same
+	// hash are up to date
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	delete(annotations, HashAnnotation)
+	obj.SetAnnotations(annotations)
+	hash, err := util.CalculateHash(obj.Object)
+	if err != nil {
+		return controllerutil.OperationResultNone, errors.Wrapf(err, "could not hash %s", objDesc)
+	}
+	annotations[HashAnnotation] = hash
+	obj.SetAnnotations(annotations)
 
 	// Get existing
 	existing := &uns.Unstructured{}
 	existing.SetGroupVersionKind(gvk)
-	err := client.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, existing)
+	err = client.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, existing)
 
 	if err != nil && apierrors.IsNotFound(err) {
-		log.Printf("does not exist, creating %s", objDesc)
+		log.Info("Object does not exist, creating")
 		err := client.Create(ctx, obj)
 		if err != nil {
-			return errors.Wrapf(err, "could not create %s", objDesc)
+			return controllerutil.OperationResultNone, errors.Wrapf(err, "could not create %s", objDesc)
 		}
-		log.Printf("successfully created %s", objDesc)
-		return nil
+		log.Info("Object created")
+		return controllerutil.OperationResultCreated, nil
 	}
 	if err != nil {
-		return errors.Wrapf(err, "could not retrieve existing %s", objDesc)
+		return controllerutil.OperationResultNone, errors.Wrapf(err, "could not retrieve existing %s", objDesc)
+	}
+
+	// Skip the update if the object was applied with the same content before.
+	// Comparing the objects themselves doesn't work, as the existing one has
+	// a status and fields defaulted by the server.
+	if existing.GetAnnotations()[HashAnnotation] == hash {
+		log.V(1).Info("Object unchanged")
+		return controllerutil.OperationResultNone, nil
 	}
 
 	// Updating existing
 	// Merge the desired object with what actually exists
 	if err := MergeMetadataForUpdate(existing, obj); err != nil {
-		return errors.Wrapf(err, "could not merge object %s with existing", objDesc)
+		return controllerutil.OperationResultNone, errors.Wrapf(err, "could not merge object %s with existing", objDesc)
 	}
-	if !equality.Semantic.DeepEqual(existing, obj) {
-		if err := client.Update(ctx, obj); err != nil {
-			return errors.Wrapf(err, "could not update object %s", objDesc)
-		}
-		log.Printf("update was successful")
-
+	if err := client.Update(ctx, obj); err != nil {
+		return controllerutil.OperationResultNone, errors.Wrapf(err, "could not update object %s", objDesc)
 	}
+	log.Info("Object updated")
 
-	return nil
+	return controllerutil.OperationResultUpdated, nil
 }
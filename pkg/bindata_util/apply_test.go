@@ -0,0 +1,206 @@
+package bindatautil
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newConfigMap(value string) *uns.Unstructured {
+	return &uns.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "config",
+			"namespace": "openstack",
+		},
+		"data": map[string]interface{}{"key": value},
+	}}
+}
+
+func TestApplyObjectSkipsUnchanged(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// objects applied before, in order
+		applied []*uns.Unstructured
+		// change of the live object between the applies
+		modify    func(*corev1.ConfigMap)
+		obj       *uns.Unstructured
+		operation controllerutil.OperationResult
+		value     string
+	}{
+		{
+			name:      "create",
+			obj:       newConfigMap("a"),
+			operation: controllerutil.OperationResultCreated,
+			value:     "a",
+		},
+		{
+			name:      "unchanged",
+			applied:   []*uns.Unstructured{newConfigMap("a")},
+			obj:       newConfigMap("a"),
+			operation: controllerutil.OperationResultNone,
+			value:     "a",
+		},
+		{
+			name:      "changed",
+			applied:   []*uns.Unstructured{newConfigMap("a")},
+			obj:       newConfigMap("b"),
+			operation: controllerutil.OperationResultUpdated,
+			value:     "b",
+		},
+		{
+			name:      "changed back",
+			applied:   []*uns.Unstructured{newConfigMap("a"), newConfigMap("b")},
+			obj:       newConfigMap("a"),
+			operation: controllerutil.OperationResultUpdated,
+			value:     "a",
+		},
+		{
+			name:    "live object changed by others",
+			applied: []*uns.Unstructured{newConfigMap("a")},
+			modify: func(cm *corev1.ConfigMap) {
+				cm.Labels = map[string]string{"other": "label"}
+			},
+			obj:       newConfigMap("a"),
+			operation: controllerutil.OperationResultNone,
+			value:     "a",
+		},
+		{
+			name:    "hash annotation removed",
+			applied: []*uns.Unstructured{newConfigMap("a")},
+			modify: func(cm *corev1.ConfigMap) {
+				delete(cm.Annotations, HashAnnotation)
+			},
+			obj:       newConfigMap("a"),
+			operation: controllerutil.OperationResultUpdated,
+			value:     "a",
+		},
+	}
+	for _, tt := range tests {
+		ctx := context.TODO()
+		c := fake.NewFakeClientWithScheme(scheme)
+		log := logf.NullLogger{}
+		for _, obj := range tt.applied {
+			if _, err := ApplyObject(ctx, c, log, obj); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		key := types.NamespacedName{Name: "config", Namespace: "openstack"}
+		if tt.modify != nil {
+			cm := &corev1.ConfigMap{}
+			if err := c.Get(ctx, key, cm); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			tt.modify(cm)
+			if err := c.Update(ctx, cm); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+
+		operation, err := ApplyObject(ctx, c, log, tt.obj)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if operation != tt.operation {
+			t.Errorf("%s: operation %s, expected %s", tt.name, operation, tt.operation)
+		}
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, key, cm); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if cm.Data["key"] != tt.value {
+			t.Errorf("%s: value %q, expected %q", tt.name, cm.Data["key"], tt.value)
+		}
+		if cm.Annotations[HashAnnotation] == "" {
+			t.Errorf("%s: hash annotation missing", tt.name)
+		}
+	}
+}
+
+// slowClient records how many Gets run at once and fails the ones of broken
+// objects
+type slowClient struct {
+	k8sclient.Client
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (c *slowClient) Get(ctx context.Context, key k8sclient.ObjectKey, obj runtime.Object) error {
+	if key.Name == "broken" {
+		return fmt.Errorf("get failed")
+	}
+	c.mu.Lock()
+	c.running++
+	if c.running > c.max {
+		c.max = c.running
+	}
+	c.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	return c.Client.Get(ctx, key, obj)
+}
+
+func TestApplyObjects(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	c := &slowClient{Client: fake.NewFakeClientWithScheme(scheme)}
+
+	objs := []*uns.Unstructured{}
+	for i := 0; i < 10; i++ {
+		obj := newConfigMap(fmt.Sprintf("%d", i))
+		obj.SetName(fmt.Sprintf("config-%d", i))
+		if i == 3 {
+			obj.SetName("broken")
+		}
+		objs = append(objs, obj)
+	}
+
+	results := ApplyObjects(context.TODO(), c, logf.NullLogger{}, objs, 3)
+	if len(results) != len(objs) {
+		t.Fatalf("expected %d results, got %d", len(objs), len(results))
+	}
+	for i, result := range results {
+		if result.Object != objs[i] {
+			t.Errorf("result %d is for %s, expected %s", i, result.Object.GetName(), objs[i].GetName())
+		}
+		if i == 3 {
+			if result.Err == nil {
+				t.Error("expected the broken object to fail")
+			}
+			continue
+		}
+		if result.Err != nil || result.Operation != controllerutil.OperationResultCreated {
+			t.Errorf("expected %s to be created, got %s %v", objs[i].GetName(), result.Operation, result.Err)
+		}
+	}
+	if c.max > 3 {
+		t.Errorf("expected at most 3 concurrent applies, got %d", c.max)
+	}
+	if c.max < 2 {
+		t.Errorf("expected concurrent applies, got %d", c.max)
+	}
+}
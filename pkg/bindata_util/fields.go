@@ -0,0 +1,178 @@
+package bindatautil
+
+import (
+	"io/fs"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+)
+
+// TemplateFields parses all manifests in a directory of fsys and returns the
+// render data fields referenced by them, e.g. Spec.Region for
+// {{ .Spec.Region }}, mapped to the manifests referencing them.
+// Fields inside of range and with blocks refer to the element and not to the
+// render data, so only $.Field references are taken from there.
+func TemplateFields(fsys fs.FS, manifestDir string, d *RenderData) (map[string][]string, error) {
+	fields := map[string][]string{}
+
+	if err := fs.WalkDir(fsys, manifestDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		// Skip non-manifest files
+		if !(strings.HasSuffix(path, ".yml") || strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".json")) {
+			return nil
+		}
+
+		source, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read manifest %s", path)
+		}
+		tmpl, err := newTemplate(path, d).Parse(string(source))
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse manifest %s as template", path)
+		}
+
+		seen := map[string]bool{}
+		add := func(field string) {
+			if !seen[field] {
+				seen[field] = true
+				fields[field] = append(fields[field], path)
+			}
+		}
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				collectFields(t.Tree.Root, true, add)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "error parsing manifests")
+	}
+
+	return fields, nil
+}
+
+// CheckRenderData compares the fields referenced by the templates with the
+// render data. missing are referenced but not in the render data, unused are
+// top level keys or fields of the render data not referenced by any template.
+// For typed render data the whole field path is checked against the type,
+// below maps and interfaces only the runtime values are known.
+func CheckRenderData(fields map[string][]string, d *RenderData) (missing []string, unused []string) {
+	used := map[string]bool{}
+	for field := range fields {
+		path := strings.Split(field, ".")
+		used[path[0]] = true
+		if !hasField(reflect.TypeOf(d.templateData()), path) {
+			missing = append(missing, field)
+		}
+	}
+	for _, key := range topLevelFields(d.templateData()) {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unused)
+	return missing, unused
+}
+
+// hasField checks if the field path can be evaluated on a value of type t
+func hasField(t reflect.Type, path []string) bool {
+	for _, name := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := t.FieldByName(name)
+			if !ok || f.PkgPath != "" {
+				return false
+			}
+			t = f.Type
+		case reflect.Map, reflect.Interface:
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// topLevelFields returns the keys of a map or the exported fields of a struct
+func topLevelFields(data interface{}) []string {
+	keys := []string{}
+	v := reflect.Indirect(reflect.ValueOf(data))
+	switch v.Kind() {
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.PkgPath == "" {
+				keys = append(keys, f.Name)
+			}
+		}
+	}
+	return keys
+}
+
+// collectFields walks the parse tree and calls add for every referenced field
+// of the render data. root tells if dot is the render data.
+func collectFields(node parse.Node, root bool, add func(string)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, root, add)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, root, add)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectFields(cmd, root, add)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectFields(arg, root, add)
+		}
+	case *parse.ChainNode:
+		collectFields(n.Node, root, add)
+	case *parse.FieldNode:
+		if root {
+			add(strings.Join(n.Ident, "."))
+		}
+	case *parse.VariableNode:
+		// $ always is the render data
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			add(strings.Join(n.Ident[1:], "."))
+		}
+	case *parse.IfNode:
+		collectFields(n.Pipe, root, add)
+		collectFields(n.List, root, add)
+		collectFields(n.ElseList, root, add)
+	case *parse.RangeNode:
+		collectFields(n.Pipe, root, add)
+		collectFields(n.List, false, add)
+		collectFields(n.ElseList, root, add)
+	case *parse.WithNode:
+		collectFields(n.Pipe, root, add)
+		collectFields(n.List, false, add)
+		collectFields(n.ElseList, root, add)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, root, add)
+	}
+}
@@ -0,0 +1,69 @@
+package bindatautil
+
+import (
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// PatchObject applies a strategic merge, merge or JSON patch, given as YAML
+// or JSON, to the rendered object.
+// Strategic merge patches need the go type of the object for the merge keys,
+// for kinds not known to the scheme, e.g. custom resources, they fall back to
+// a merge patch like kubectl does.
+func PatchObject(obj *uns.Unstructured, patchType types.PatchType, patch string, scheme *runtime.Scheme) error {
+	patchJSON, err := yaml.YAMLToJSON([]byte(patch))
+	if err != nil {
+		return errors.Wrap(err, "patch is neither valid YAML nor JSON")
+	}
+	original, err := json.Marshal(obj.Object)
+	if err != nil {
+		return errors.Wrapf(err, "could not marshal %s", obj.GetName())
+	}
+
+	var patched []byte
+	switch patchType {
+	case types.JSONPatchType:
+		p, err := jsonpatch.DecodePatch(patchJSON)
+		if err != nil {
+			return errors.Wrap(err, "invalid JSON patch")
+		}
+		patched, err = p.Apply(original)
+		if err != nil {
+			return errors.Wrap(err, "could not apply JSON patch")
+		}
+	case types.StrategicMergePatchType:
+		typed, err := scheme.New(obj.GroupVersionKind())
+		if err == nil {
+			patched, err = strategicpatch.StrategicMergePatch(original, patchJSON, typed)
+			if err != nil {
+				return errors.Wrap(err, "could not apply strategic merge patch")
+			}
+			break
+		}
+		fallthrough
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, patchJSON)
+		if err != nil {
+			return errors.Wrap(err, "could not apply merge patch")
+		}
+	default:
+		return errors.Errorf("unsupported patch type %s", patchType)
+	}
+
+	// keep integers as int64 like the decoded manifests
+	out := map[string]interface{}{}
+	if err := utiljson.Unmarshal(patched, &out); err != nil {
+		return errors.Wrap(err, "could not unmarshal patched object")
+	}
+	obj.SetUnstructuredContent(out)
+	return nil
+}
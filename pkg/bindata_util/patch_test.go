@@ -0,0 +1,169 @@
+package bindatautil
+
+import (
+	"reflect"
+	"testing"
+
+	uns "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func newDeployment() *uns.Unstructured {
+	return &uns.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "api"},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "api", "image": "api:1"},
+						map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func newCustomResource() *uns.Unstructured {
+	return &uns.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "keystone.openstack.org/v1",
+		"kind":       "KeystoneAPI",
+		"metadata":   map[string]interface{}{"name": "keystone"},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"list":     []interface{}{"a", "b"},
+		},
+	}}
+}
+
+func TestPatchObject(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		obj       *uns.Unstructured
+		patchType types.PatchType
+		patch     string
+		path      []string
+		expected  interface{}
+		invalid   bool
+	}{
+		{
+			name:      "strategic merge patch merges containers by name",
+			obj:       newDeployment(),
+			patchType: types.StrategicMergePatchType,
+			patch:     "spec:\n  template:\n    spec:\n      containers:\n      - name: sidecar\n        image: sidecar:2\n",
+			path:      []string{"spec", "template", "spec", "containers"},
+			expected: []interface{}{
+				map[string]interface{}{"name": "api", "image": "api:1"},
+				map[string]interface{}{"name": "sidecar", "image": "sidecar:2"},
+			},
+		},
+		{
+			name:      "strategic merge patch falls back to a merge patch for unknown kinds",
+			obj:       newCustomResource(),
+			patchType: types.StrategicMergePatchType,
+			patch:     `{"spec": {"list": ["c"]}}`,
+			path:      []string{"spec", "list"},
+			expected:  []interface{}{"c"},
+		},
+		{
+			name:      "merge patch replaces lists",
+			obj:       newDeployment(),
+			patchType: types.MergePatchType,
+			patch:     "spec:\n  template:\n    spec:\n      containers:\n      - name: sidecar\n        image: sidecar:2\n",
+			path:      []string{"spec", "template", "spec", "containers"},
+			expected: []interface{}{
+				map[string]interface{}{"name": "sidecar", "image": "sidecar:2"},
+			},
+		},
+		{
+			name:      "merge patch removes fields set to null",
+			obj:       newCustomResource(),
+			patchType: types.MergePatchType,
+			patch:     "spec:\n  list: null\n",
+			path:      []string{"spec"},
+			expected:  map[string]interface{}{"replicas": int64(1)},
+		},
+		{
+			name:      "json patch",
+			obj:       newDeployment(),
+			patchType: types.JSONPatchType,
+			patch:     `[{"op": "replace", "path": "/spec/template/spec/containers/0/image", "value": "api:2"}]`,
+			path:      []string{"spec", "template", "spec", "containers", "0", "image"},
+			expected:  "api:2",
+		},
+		{
+			name:      "json patch given as yaml",
+			obj:       newDeployment(),
+			patchType: types.JSONPatchType,
+			patch:     "- op: replace\n  path: /spec/replicas\n  value: 3\n",
+			path:      []string{"spec", "replicas"},
+			expected:  int64(3),
+		},
+		{
+			name:      "json patch of a missing path",
+			obj:       newDeployment(),
+			patchType: types.JSONPatchType,
+			patch:     `[{"op": "replace", "path": "/spec/missing/field", "value": 1}]`,
+			invalid:   true,
+		},
+		{
+			name:      "invalid patch",
+			obj:       newDeployment(),
+			patchType: types.MergePatchType,
+			patch:     "spec: [",
+			invalid:   true,
+		},
+		{
+			name:      "unsupported patch type",
+			obj:       newDeployment(),
+			patchType: types.ApplyPatchType,
+			patch:     "spec: {}",
+			invalid:   true,
+		},
+	}
+	for _, tt := range tests {
+		err := PatchObject(tt.obj, tt.patchType, tt.patch, scheme)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if value := getPath(tt.obj.Object, tt.path); !reflect.DeepEqual(value, tt.expected) {
+			t.Errorf("%s: got %#v, expected %#v", tt.name, value, tt.expected)
+		}
+	}
+}
+
+// getPath returns the value at path, list items are addressed by index
+func getPath(obj interface{}, path []string) interface{} {
+	for _, key := range path {
+		switch o := obj.(type) {
+		case map[string]interface{}:
+			obj = o[key]
+		case []interface{}:
+			i := int(key[0] - '0')
+			if i >= len(o) {
+				return nil
+			}
+			obj = o[i]
+		default:
+			return nil
+		}
+	}
+	return obj
+}
@@ -3,9 +3,7 @@ package bindatautil
 import (
 	"bytes"
 	"io"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"io/fs"
 	"strings"
 	"text/template"
 
@@ -22,6 +20,8 @@ import (
 type RenderData struct {
 	Funcs template.FuncMap
 	Data  map[string]interface{}
+	// Values - typed data, the templates get executed with it instead of Data if set
+	Values interface{}
 }
 
 // MakeRenderData -
@@ -32,16 +32,32 @@ func MakeRenderData() RenderData {
 	}
 }
 
-// RenderDir will render all manifests in a directory, descending in to subdirectories
+// MakeTypedRenderData returns render data executing the templates with
+// values, usually a struct, instead of the Data map
+func MakeTypedRenderData(values interface{}) RenderData {
+	d := MakeRenderData()
+	d.Values = values
+	return d
+}
+
+// templateData returns the data the templates get executed with
+func (d *RenderData) templateData() interface{} {
+	if d.Values != nil {
+		return d.Values
+	}
+	return d.Data
+}
+
+// RenderDir will render all manifests in a directory of fsys, descending in to subdirectories
 // It will perform template substitutions based on the data supplied by the RenderData
-func RenderDir(manifestDir string, d *RenderData) ([]*unstructured.Unstructured, error) {
+func RenderDir(fsys fs.FS, manifestDir string, d *RenderData) ([]*unstructured.Unstructured, error) {
 	out := []*unstructured.Unstructured{}
 
-	if err := filepath.Walk(manifestDir, func(path string, info os.FileInfo, err error) error {
+	if err := fs.WalkDir(fsys, manifestDir, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
+		if entry.IsDir() {
 			return nil
 		}
 
@@ -50,7 +66,7 @@ func RenderDir(manifestDir string, d *RenderData) ([]*unstructured.Unstructured,
 			return nil
 		}
 
-		objs, err := RenderTemplate(path, d)
+		objs, err := RenderTemplate(fsys, path, d)
 		if err != nil {
 			return err
 		}
@@ -64,18 +80,11 @@ func RenderDir(manifestDir string, d *RenderData) ([]*unstructured.Unstructured,
 }
 
 // RenderTemplate reads, renders, and attempts to parse a yaml or
-// json file representing one or more k8s api objects
-func RenderTemplate(path string, d *RenderData) ([]*unstructured.Unstructured, error) {
-	tmpl := template.New(path).Option("missingkey=error")
-	if d.Funcs != nil {
-		tmpl.Funcs(d.Funcs)
-	}
+// json file of fsys representing one or more k8s api objects
+func RenderTemplate(fsys fs.FS, path string, d *RenderData) ([]*unstructured.Unstructured, error) {
+	tmpl := newTemplate(path, d)
 
-	// Add universal functions
-	tmpl.Funcs(template.FuncMap{"getOr": util.GetOr, "isSet": util.IsSet})
-	tmpl.Funcs(sprig.TxtFuncMap())
-
-	source, err := ioutil.ReadFile(path)
+	source, err := fs.ReadFile(fsys, path)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to read manifest %s", path)
 	}
@@ -85,7 +94,7 @@ func RenderTemplate(path string, d *RenderData) ([]*unstructured.Unstructured, e
 	}
 
 	rendered := bytes.Buffer{}
-	if err := tmpl.Execute(&rendered, d.Data); err != nil {
+	if err := tmpl.Execute(&rendered, d.templateData()); err != nil {
 		return nil, errors.Wrapf(err, "failed to render manifest %s", path)
 	}
 
@@ -110,3 +119,16 @@ func RenderTemplate(path string, d *RenderData) ([]*unstructured.Unstructured, e
 
 	return out, nil
 }
+
+// newTemplate returns a template with the render data and universal functions
+func newTemplate(path string, d *RenderData) *template.Template {
+	tmpl := template.New(path).Option("missingkey=error")
+	if d.Funcs != nil {
+		tmpl.Funcs(d.Funcs)
+	}
+
+	// Add universal functions
+	tmpl.Funcs(template.FuncMap{"getOr": util.GetOr, "isSet": util.IsSet})
+	tmpl.Funcs(sprig.TxtFuncMap())
+	return tmpl
+}
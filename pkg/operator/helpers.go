@@ -117,6 +117,21 @@ func getOperatorRules() *[]rbacv1.PolicyRule {
 				"update",
 			},
 		},
+		{
+			APIGroups: []string{
+				"apps",
+			},
+			Resources: []string{
+				"deployments",
+				"replicasets",
+				"statefulsets",
+			},
+			Verbs: []string{
+				"get",
+				"list",
+				"watch",
+			},
+		},
 		{
 			APIGroups: []string{
 				"controlplane.openstack.org",
@@ -125,11 +140,74 @@ func getOperatorRules() *[]rbacv1.PolicyRule {
 				"*",
 				"controlplanes",
 				"openstackclients",
+				"openstackcommands",
+				"openstackbootstraps",
+				"controlplanebackups",
+				"controlplanerestores",
 			},
 			Verbs: []string{
 				"*",
 			},
 		},
+		{
+			APIGroups: []string{
+				"batch",
+			},
+			Resources: []string{
+				"jobs",
+				"cronjobs",
+			},
+			Verbs: []string{
+				"*",
+			},
+		},
+		{
+			APIGroups: []string{
+				"policy",
+			},
+			Resources: []string{
+				"poddisruptionbudgets",
+			},
+			Verbs: []string{
+				"*",
+			},
+		},
+		{
+			APIGroups: []string{
+				"route.openshift.io",
+			},
+			Resources: []string{
+				"routes",
+				"routes/custom-host",
+			},
+			Verbs: []string{
+				"*",
+			},
+		},
+		{
+			APIGroups: []string{
+				"networking.k8s.io",
+			},
+			Resources: []string{
+				"ingresses",
+			},
+			Verbs: []string{
+				"*",
+			},
+		},
+		{
+			APIGroups: []string{
+				"snapshot.storage.k8s.io",
+			},
+			Resources: []string{
+				"volumesnapshots",
+			},
+			Verbs: []string{
+				"get",
+				"list",
+				"create",
+			},
+		},
 		{
 			APIGroups: []string{
 				"database.openstack.org",
@@ -320,6 +398,11 @@ func GetCSVBase(name, namespace, displayName, description, image, replaces strin
 					"replicas": 1,
 				},
 				"storage_class": "host-nfs-storageclass",
+				"region":        "regionOne",
+				"exposure": map[string]interface{}{
+					"type":           "route",
+					"tlsTermination": "edge",
+				},
 			},
 		},
 		map[string]interface{}{
@@ -335,6 +418,67 @@ func GetCSVBase(name, namespace, displayName, description, image, replaces strin
 				"openStackConfigSecret": "openstack-config-secret",
 			},
 		},
+		map[string]interface{}{
+			"apiVersion": "controlplane.openstack.org/v1beta1",
+			"kind":       "OpenStackCommand",
+			"metadata": map[string]string{
+				"name":      "create-flavors",
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"openStackClient": "openstackclient",
+				"commands": []string{
+					"flavor create --ram 512 --disk 1 --vcpus 1 m1.tiny",
+				},
+				"backoffLimit":            3,
+				"ttlSecondsAfterFinished": 3600,
+			},
+		},
+		map[string]interface{}{
+			"apiVersion": "controlplane.openstack.org/v1beta1",
+			"kind":       "OpenStackBootstrap",
+			"metadata": map[string]string{
+				"name":      "openstack-bootstrap",
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"controlPlane":    "openstack-ctlplane",
+				"openStackClient": "openstackclient",
+				"flavors": []interface{}{
+					map[string]interface{}{
+						"name":  "m1.small",
+						"vcpus": 1,
+						"ram":   2048,
+						"disk":  20,
+					},
+				},
+			},
+		},
+		map[string]interface{}{
+			"apiVersion": "controlplane.openstack.org/v1beta1",
+			"kind":       "ControlPlaneBackup",
+			"metadata": map[string]string{
+				"name":      "openstack-ctlplane-backup",
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"controlPlane": "openstack-ctlplane",
+				"target": map[string]interface{}{
+					"persistentVolumeClaim": "openstack-ctlplane-backups",
+				},
+			},
+		},
+		map[string]interface{}{
+			"apiVersion": "controlplane.openstack.org/v1beta1",
+			"kind":       "ControlPlaneRestore",
+			"metadata": map[string]string{
+				"name":      "openstack-ctlplane-restore",
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"backup": "openstack-ctlplane-backup",
+			},
+		},
 		map[string]interface{}{
 			"apiVersion": "compute-node.openstack.org/v1alpha1",
 			"kind":       "ComputeNodeOpenStack",
@@ -463,6 +607,34 @@ func GetCSVBase(name, namespace, displayName, description, image, replaces strin
 						DisplayName: "OpenStack Client",
 						Description: "Represents a OpenStack Client Deployment for the " + crdDisplay,
 					},
+					csvv1alpha1.CRDDescription{
+						Name:        "openstackcommands.controlplane.openstack.org",
+						Version:     "v1beta1",
+						Kind:        "OpenStackCommand",
+						DisplayName: "OpenStack Command",
+						Description: "Represents OpenStack CLI commands run as a Job for the " + crdDisplay,
+					},
+					csvv1alpha1.CRDDescription{
+						Name:        "openstackbootstraps.controlplane.openstack.org",
+						Version:     "v1beta1",
+						Kind:        "OpenStackBootstrap",
+						DisplayName: "OpenStack Bootstrap",
+						Description: "Represents the initial OpenStack resources of a Control Plane for the " + crdDisplay,
+					},
+					csvv1alpha1.CRDDescription{
+						Name:        "controlplanebackups.controlplane.openstack.org",
+						Version:     "v1beta1",
+						Kind:        "ControlPlaneBackup",
+						DisplayName: "Control Plane Backup",
+						Description: "Represents a database dump of a Control Plane for the " + crdDisplay,
+					},
+					csvv1alpha1.CRDDescription{
+						Name:        "controlplanerestores.controlplane.openstack.org",
+						Version:     "v1beta1",
+						Kind:        "ControlPlaneRestore",
+						DisplayName: "Control Plane Restore",
+						Description: "Represents the restore of a Control Plane database dump for the " + crdDisplay,
+					},
 				},
 				Required: []csvv1alpha1.CRDDescription{},
 			},
@@ -0,0 +1,44 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateINI checks that content parses as an oslo.config style INI file:
+// sections in brackets, "key = value" or "key: value" options inside of a
+// section, comments starting with # or ; and indented continuation lines.
+func ValidateINI(content string) error {
+	section := ""
+	option := false
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+
+		// indented lines continue the value of the previous option
+		if option && (line[0] == ' ' || line[0] == '\t') {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") {
+			if !strings.HasSuffix(trimmed, "]") || strings.TrimSpace(trimmed[1:len(trimmed)-1]) == "" {
+				return fmt.Errorf("line %d: invalid section header %q", i+1, trimmed)
+			}
+			section = trimmed[1 : len(trimmed)-1]
+			option = false
+			continue
+		}
+
+		sep := strings.IndexAny(trimmed, "=:")
+		if sep < 1 || strings.TrimSpace(trimmed[:sep]) == "" {
+			return fmt.Errorf("line %d: expected key = value, got %q", i+1, trimmed)
+		}
+		if section == "" {
+			return fmt.Errorf("line %d: option %q outside of a section", i+1, strings.TrimSpace(trimmed[:sep]))
+		}
+		option = true
+	}
+	return nil
+}
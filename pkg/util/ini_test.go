@@ -0,0 +1,34 @@
+package util
+
+import "testing"
+
+func TestValidateINI(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		invalid bool
+	}{
+		{name: "empty", content: ""},
+		{name: "options", content: "[DEFAULT]\ndebug = true\nverbose: false\n"},
+		{name: "comments", content: "# comment\n; comment\n[DEFAULT]\n  # indented comment\ndebug=true\n"},
+		{name: "continuation lines", content: "[DEFAULT]\nvalue = first\n  second\n\tthird\n"},
+		{name: "empty value", content: "[DEFAULT]\nkey =\n"},
+		{name: "several sections", content: "[DEFAULT]\na = 1\n\n[database]\nconnection = mysql+pymysql://u:p@host/db\n"},
+		{name: "option outside of a section", content: "debug = true\n[DEFAULT]\n", invalid: true},
+		{name: "unterminated section", content: "[DEFAULT\ndebug = true\n", invalid: true},
+		{name: "empty section", content: "[ ]\ndebug = true\n", invalid: true},
+		{name: "missing separator", content: "[DEFAULT]\ndebug true\n", invalid: true},
+		{name: "missing key", content: "[DEFAULT]\n= true\n", invalid: true},
+		{name: "indented option", content: "[DEFAULT]\n  indented = value\n"},
+		{name: "continuation after section", content: "[DEFAULT]\n  value\n", invalid: true},
+	}
+	for _, tt := range tests {
+		err := ValidateINI(tt.content)
+		if tt.invalid && err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if !tt.invalid && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
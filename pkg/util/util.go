@@ -2,8 +2,10 @@ package util
 
 import (
 	"crypto/md5"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"math/big"
 )
 
 // CalculateHash computes MD5 sum of the JSONfied object passed as obj.
@@ -15,3 +17,18 @@ func CalculateHash(obj interface{}) (string, error) {
 	configSum := md5.Sum(configStr)
 	return fmt.Sprintf("%x", configSum), nil
 }
+
+const passwordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// GeneratePassword returns a random alphanumeric password of the given length
+func GeneratePassword(length int) (string, error) {
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordChars))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordChars[n.Int64()]
+	}
+	return string(b), nil
+}